@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,6 +10,8 @@ import (
 	"path/filepath"
 	"syscall"
 	"time"
+	// image alpine tidak membawa tzdata, dibutuhkan untuk quiet hours notifikasi
+	_ "time/tzdata"
 
 	"backend/configs"
 	_ "backend/docs"
@@ -17,7 +20,9 @@ import (
 	"backend/internal/delivery/http/routes"
 	"backend/internal/infrastructure/database"
 	"backend/internal/infrastructure/email"
+	"backend/internal/infrastructure/messaging"
 	"backend/internal/infrastructure/oauth"
+	"backend/internal/infrastructure/push"
 	"backend/internal/infrastructure/storage"
 	"backend/internal/repository/postgres"
 	"backend/internal/usecase"
@@ -37,14 +42,14 @@ func main() {
 	// Initialize database connection
 	db, err := database.NewPostgresConnection(&config.Database)
 	if err != nil {
-		log.Fatal("Failed to connect to database: %v", err)
+		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
 	// Initialize database schema
 	err = database.InitDatabase(db)
 	if err != nil {
-		log.Fatal("Failed to initialize database schema: %v", err)
+		log.Fatalf("Failed to initialize database schema: %v", err)
 	}
 
 	// Initialize repositories
@@ -55,6 +60,20 @@ func main() {
 	historyRepo := postgres.NewHistoryRepository(db)
 	rewardRepo := postgres.NewRewardRepository(db)
 	messageRepo := postgres.NewMessageRepository(db)
+	bloodRequestRepo := postgres.NewBloodRequestRepository(db)
+	pledgeRepo := postgres.NewPledgeRepository(db)
+	facilityRepo := postgres.NewFacilityRepository(db)
+	facilityStockRepo := postgres.NewFacilityStockRepository(db)
+	topicSubscriptionRepo := postgres.NewTopicSubscriptionRepository(db)
+	notificationRepo := postgres.NewNotificationRepository(db)
+	outboxRepo := postgres.NewOutboxRepository(db)
+	deviceTokenRepo := postgres.NewDeviceTokenRepository(db)
+	notificationPreferenceRepo := postgres.NewNotificationPreferenceRepository(db)
+	broadcastRepo := postgres.NewBroadcastRepository(db)
+	featureFlagRepo := postgres.NewFeatureFlagRepository(db)
+	messagingRepo := postgres.NewMessagingRepository(db)
+	donationReminderRepo := postgres.NewDonationReminderRepository(db)
+	txManager := postgres.NewTxManager(db)
 
 	// Initialize services
 	jwtService := jwt.NewJWTService(config.JWT.Secret, config.JWT.ExpireTime)
@@ -96,19 +115,64 @@ func main() {
 		log.Fatalf("Failed to initialize storage: %v", err)
 	}
 
+	var pushProvider push.PushProvider
+
+	switch config.Push.Provider {
+	case "fcm":
+		pushProvider, err = push.NewFCMProvider(
+			config.Push.FCMCredentialsFile,
+			config.Push.FCMBaseURL,
+			config.Push.FCMIIDBaseURL,
+		)
+	case "recording":
+		pushProvider = push.NewRecordingProvider()
+	default:
+		err = fmt.Errorf("unknown PUSH_PROVIDER %q, expected fcm or recording", config.Push.Provider)
+	}
+
+	if err != nil {
+		log.Fatalf("Failed to initialize push provider: %v", err)
+	}
+
+	var messagingGateway messaging.MessagingGateway
+
+	switch config.Messaging.Provider {
+	case "http":
+		messagingGateway = messaging.NewHTTPGateway(config.Messaging.BaseURL, config.Messaging.APIKey, config.Messaging.Sender)
+	case "log":
+		messagingGateway = messaging.NewLogGateway()
+	default:
+		log.Fatalf("Failed to initialize messaging gateway: unknown MESSAGING_PROVIDER %q, expected http or log", config.Messaging.Provider)
+	}
+
 	// Initialize use cases
-	fcmUseCase := usecase.NewFcmUseCase(userRepo)
-	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, jwtService, fcmUseCase, emailService, googleOauth)
-	profileUseCase := usecase.NewProfileUseCase(userRepo, fileStorage)
+	notificationPreferenceUseCase := usecase.NewNotificationPreferenceUseCase(notificationPreferenceRepo)
+	messagingUseCase := usecase.NewMessagingUseCase(messagingGateway, txManager, messagingRepo, notificationPreferenceRepo, userRepo, outboxRepo)
+	fcmUseCase := usecase.NewFcmUseCase(pushProvider, userRepo, bloodRequestRepo, notificationRepo, topicSubscriptionRepo, outboxRepo, deviceTokenRepo, notificationPreferenceUseCase, messagingUseCase)
+	topicSubscriptionUseCase := usecase.NewTopicSubscriptionUseCase(topicSubscriptionRepo, deviceTokenRepo, userRepo, fcmUseCase)
+	deviceTokenUseCase := usecase.NewDeviceTokenUseCase(deviceTokenRepo, topicSubscriptionUseCase)
+	authUseCase := usecase.NewAuthUseCase(userRepo, tokenRepo, jwtService, fcmUseCase, deviceTokenUseCase, emailService, googleOauth)
+	profileUseCase := usecase.NewProfileUseCase(userRepo, fileStorage, topicSubscriptionUseCase)
 	educationUseCase := usecase.NewEducationUseCase(educationRepo, fileStorage)
 	uploadEvidenceUseCase := usecase.NewUploadEvidenceUseCase(uploadEvidenceRepo, fileStorage)
-	historyUseCase := usecase.NewHistoryUseCase(historyRepo, fileStorage)
+	historyUseCase := usecase.NewHistoryUseCase(txManager, historyRepo, userRepo, bloodRequestRepo, pledgeRepo, facilityRepo, outboxRepo, fileStorage)
 	chatbotUseCase := usecase.NewChatbotUsecase(config.ChatBot)
 	rewardUseCase := usecase.NewRewardUseCase(config.Reloadly, userRepo, rewardRepo)
 	messageUseCase := usecase.NewMessageUseCase(messageRepo)
+	chatNotificationUseCase := usecase.NewChatNotificationUseCase(config.ChatPush, userRepo, fcmUseCase)
+	bloodRequestUseCase := usecase.NewBloodRequestUseCase(bloodRequestRepo, userRepo, historyRepo, facilityRepo)
+	facilityUseCase := usecase.NewFacilityUseCase(facilityRepo, userRepo)
+	facilityStockUseCase := usecase.NewFacilityStockUseCase(config.Stock, txManager, facilityRepo, facilityStockRepo, userRepo, outboxRepo)
+	escalationUseCase := usecase.NewEscalationUseCase(config.Escalation, txManager, bloodRequestRepo, outboxRepo, fcmUseCase)
+	notificationUseCase := usecase.NewNotificationUseCase(notificationRepo)
+	donationReminderUseCase := usecase.NewDonationReminderUseCase(config.Reminder, donationReminderRepo, userRepo, bloodRequestRepo, facilityRepo, notificationPreferenceUseCase, fcmUseCase, emailService)
+	broadcastUseCase := usecase.NewBroadcastUseCase(config.Broadcast, txManager, broadcastRepo, bloodRequestRepo, userRepo, featureFlagRepo, outboxRepo)
+	outboxUseCase := usecase.NewOutboxUseCase(outboxRepo, userRepo, facilityStockRepo, featureFlagRepo, fcmUseCase, messagingUseCase, escalationUseCase)
+	pledgeUseCase := usecase.NewPledgeUseCase(txManager, pledgeRepo, bloodRequestRepo, userRepo, messageRepo, bloodRequestUseCase)
 
 	// Initialize HTTP handlers
-	authHandler := handler.NewAuthHandler(authUseCase, jwtService, googleOauth, fileStorage.(*storage.S3Storage))
+	messageHandler := handler.NewWebSockerHandler(messageUseCase, chatNotificationUseCase)
+	authHandler := handler.NewAuthHandler(authUseCase, jwtService, googleOauth, fileStorage.(*storage.S3Storage), messageHandler)
 	authMiddleware := middleware.NewAuthMiddleware(jwtService, tokenRepo)
 	profileHandler := handler.NewProfileHandler(profileUseCase)
 	educationHandler := handler.NewEducationHandler(educationUseCase, fileStorage.(*storage.S3Storage))
@@ -116,14 +180,30 @@ func main() {
 	historyHandler := handler.NewHistoryHandler(historyUseCase, authUseCase, fileStorage.(*storage.S3Storage))
 	chatbotHandler := handler.NewChatbotHandler(chatbotUseCase)
 	rewardHanlder := handler.NewRewardHandler(rewardUseCase)
-	fcmHandler := handler.NewFcmHandler(fcmUseCase)
-	messageHandler := handler.NewWebSockerHandler(messageUseCase)
+	fcmHandler := handler.NewFcmHandler(broadcastUseCase)
+	bloodRequestHandler := handler.NewBloodRequestHandler(bloodRequestUseCase, escalationUseCase)
+	facilityHandler := handler.NewFacilityHandler(facilityUseCase, facilityStockUseCase)
+	notificationHandler := handler.NewNotificationHandler(notificationUseCase, notificationPreferenceUseCase)
+	outboxHandler := handler.NewOutboxHandler(outboxUseCase)
+	deviceTokenHandler := handler.NewDeviceTokenHandler(deviceTokenUseCase)
+	messagingHandler := handler.NewMessagingHandler(messagingUseCase, config.Messaging.WebhookSecret)
+	conversationHandler := handler.NewConversationHandler(messageUseCase)
+	pledgeHandler := handler.NewPledgeHandler(pledgeUseCase, messageHandler)
 
 	go messageHandler.HandleMessages()
 
+	// Background workers, dihentikan saat server shutdown
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go bloodRequestUseCase.RunExpirySweeper(workerCtx, time.Minute)
+	go escalationUseCase.RunEscalationScheduler(workerCtx, time.Minute)
+	go outboxUseCase.RunOutboxWorker(workerCtx, 5*time.Second)
+	go donationReminderUseCase.RunReminderScheduler(workerCtx, time.Hour)
+
 	// Initialize router
 	router := mux.NewRouter()
-	routes.SetupRoutes(router, authHandler, authMiddleware, profileHandler, educationHandler, uploadEvidenceHandler, historyHandler, chatbotHandler, rewardHanlder, fcmHandler, messageHandler)
+	routes.SetupRoutes(router, authHandler, authMiddleware, profileHandler, educationHandler, uploadEvidenceHandler, historyHandler, chatbotHandler, rewardHanlder, fcmHandler, messageHandler, bloodRequestHandler, pledgeHandler, facilityHandler, notificationHandler, outboxHandler, deviceTokenHandler, messagingHandler, conversationHandler)
 
 	// Configure HTTP server
 	server := &http.Server{
@@ -138,7 +218,7 @@ func main() {
 	go func() {
 		log.Printf("Starting server on port %s", config.Server.Port)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Server error: %v", err)
+			log.Fatalf("Server error: %v", err)
 		}
 	}()
 
@@ -148,6 +228,7 @@ func main() {
 	<-quit
 
 	log.Printf("Shutting down server...")
+	stopWorkers()
 
 	// Create a deadline to wait for
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -4,19 +4,27 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Email    EmailConfig
-	Google   GoogleOAuthConfig
-	Storage  StorageConfig
-	ChatBot  ChatBotConfig
-	Reloadly Reward
+	Server     ServerConfig
+	Database   DatabaseConfig
+	JWT        JWTConfig
+	Email      EmailConfig
+	Google     GoogleOAuthConfig
+	Storage    StorageConfig
+	ChatBot    ChatBotConfig
+	Reloadly   Reward
+	Escalation EscalationConfig
+	Stock      StockConfig
+	Push       PushConfig
+	ChatPush   ChatPushConfig
+	Broadcast  BroadcastConfig
+	Messaging  MessagingConfig
+	Reminder   ReminderConfig
 }
 
 type ServerConfig struct {
@@ -74,6 +82,78 @@ type Reward struct {
 	Audience     string
 }
 
+// EscalationConfig mengatur jeda tanpa pledge sebelum blood request dieskalasi
+type EscalationConfig struct {
+	NormalWindow   time.Duration
+	UrgentWindow   time.Duration
+	CriticalWindow time.Duration
+	WideRadiusKm   float64
+}
+
+// ReminderConfig mengatur pengingat "sudah bisa donor lagi"
+type ReminderConfig struct {
+	// DaysBefore: pengingat dikirim sekian hari sebelum tanggal boleh donor, 0 berarti di hari itu
+	DaysBefore int
+	RadiusKm   float64
+	BatchSize  int
+}
+
+// StockConfig mengatur batas stok darah fasilitas sebelum shortage dinaikkan
+type StockConfig struct {
+	ShortageThreshold int
+	AlertRadiusKm     float64
+}
+
+// PushConfig mengatur provider push notification, "fcm" atau "recording" untuk development
+type PushConfig struct {
+	Provider           string
+	FCMCredentialsFile string
+	FCMBaseURL         string
+	FCMIIDBaseURL      string
+}
+
+// ChatPushConfig mengatur push untuk pesan chat yang diterima saat user offline.
+// GracePeriod adalah jeda sebelum push dikirim (batal jika user tersambung lagi), pesan
+// dalam CollapseWindow digabung menjadi satu notifikasi.
+type ChatPushConfig struct {
+	GracePeriod    time.Duration
+	CollapseWindow time.Duration
+}
+
+// BroadcastConfig membatasi broadcast pencari: jumlah per user dan per request dalam Window,
+// serta jeda sebelum broadcast dengan isi yang sama boleh dikirim ulang
+type BroadcastConfig struct {
+	PerUserLimit    int
+	PerRequestLimit int
+	Window          time.Duration
+	DuplicateWindow time.Duration
+}
+
+// MessagingConfig mengatur gateway SMS / WhatsApp, "http" atau "log" untuk development.
+// WebhookSecret dicocokkan dengan header X-Webhook-Secret pada callback status dari provider.
+type MessagingConfig struct {
+	Provider      string
+	BaseURL       string
+	APIKey        string
+	Sender        string
+	WebhookSecret string
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func LoadConfig() (*Config, error) {
 	if _, err := os.Stat(".env"); err == nil {
 		if err := godotenv.Load(); err != nil {
@@ -131,5 +211,43 @@ func LoadConfig() (*Config, error) {
 			GrantType:    os.Getenv("RELOADLY_GRANT_TYPE"),
 			Audience:     os.Getenv("RELOADLY_AUDIENCE"),
 		},
+		Escalation: EscalationConfig{
+			NormalWindow:   time.Duration(getEnvInt("ESCALATION_WINDOW_NORMAL_MINUTES", 360)) * time.Minute,
+			UrgentWindow:   time.Duration(getEnvInt("ESCALATION_WINDOW_URGENT_MINUTES", 60)) * time.Minute,
+			CriticalWindow: time.Duration(getEnvInt("ESCALATION_WINDOW_CRITICAL_MINUTES", 15)) * time.Minute,
+			WideRadiusKm:   float64(getEnvInt("ESCALATION_WIDE_RADIUS_KM", 75)),
+		},
+		Reminder: ReminderConfig{
+			DaysBefore: getEnvInt("REMINDER_DAYS_BEFORE", 0),
+			RadiusKm:   float64(getEnvInt("REMINDER_RADIUS_KM", 25)),
+			BatchSize:  getEnvInt("REMINDER_BATCH_SIZE", 200),
+		},
+		Stock: StockConfig{
+			ShortageThreshold: getEnvInt("STOCK_SHORTAGE_THRESHOLD", 10),
+			AlertRadiusKm:     float64(getEnvInt("STOCK_ALERT_RADIUS_KM", 30)),
+		},
+		Push: PushConfig{
+			Provider:           getEnv("PUSH_PROVIDER", "fcm"),
+			FCMCredentialsFile: getEnv("FCM_CREDENTIALS_FILE", "internal/infrastructure/broadcast/donora-f67f2-5c889d5acd0a.json"),
+			FCMBaseURL:         getEnv("FCM_BASE_URL", "https://fcm.googleapis.com"),
+			FCMIIDBaseURL:      getEnv("FCM_IID_BASE_URL", "https://iid.googleapis.com"),
+		},
+		ChatPush: ChatPushConfig{
+			GracePeriod:    time.Duration(getEnvInt("CHAT_PUSH_GRACE_SECONDS", 30)) * time.Second,
+			CollapseWindow: time.Duration(getEnvInt("CHAT_PUSH_COLLAPSE_SECONDS", 300)) * time.Second,
+		},
+		Broadcast: BroadcastConfig{
+			PerUserLimit:    getEnvInt("BROADCAST_PER_USER_LIMIT", 5),
+			PerRequestLimit: getEnvInt("BROADCAST_PER_REQUEST_LIMIT", 3),
+			Window:          time.Duration(getEnvInt("BROADCAST_WINDOW_HOURS", 24)) * time.Hour,
+			DuplicateWindow: time.Duration(getEnvInt("BROADCAST_DUPLICATE_MINUTES", 120)) * time.Minute,
+		},
+		Messaging: MessagingConfig{
+			Provider:      getEnv("MESSAGING_PROVIDER", "log"),
+			BaseURL:       os.Getenv("MESSAGING_BASE_URL"),
+			APIKey:        os.Getenv("MESSAGING_API_KEY"),
+			Sender:        os.Getenv("MESSAGING_SENDER"),
+			WebhookSecret: os.Getenv("MESSAGING_WEBHOOK_SECRET"),
+		},
 	}, nil
 }
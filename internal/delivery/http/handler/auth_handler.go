@@ -9,25 +9,29 @@ import (
 	"mime/multipart"
 
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 )
 
 type AuthHandler struct {
-	authUseCase usecase.AuthUseCase
-	googleOauth *oauth.GooogleOauth
-	jwtService  *jwt.JWTService
-	storage     *storage.S3Storage
+	authUseCase      usecase.AuthUseCase
+	googleOauth      *oauth.GooogleOauth
+	jwtService       *jwt.JWTService
+	storage          *storage.S3Storage
+	webSocketHandler *WebSocketHandler
 }
 
-func NewAuthHandler(authUseCase usecase.AuthUseCase, jwtService *jwt.JWTService, googleOauth *oauth.GooogleOauth, storage *storage.S3Storage) *AuthHandler {
+func NewAuthHandler(authUseCase usecase.AuthUseCase, jwtService *jwt.JWTService, googleOauth *oauth.GooogleOauth, storage *storage.S3Storage, webSocketHandler *WebSocketHandler) *AuthHandler {
 	return &AuthHandler{
-		authUseCase: authUseCase,
-		googleOauth: googleOauth,
-		jwtService:  jwtService,
-		storage:     storage,
+		authUseCase:      authUseCase,
+		googleOauth:      googleOauth,
+		jwtService:       jwtService,
+		storage:          storage,
+		webSocketHandler: webSocketHandler,
 	}
 }
 
@@ -54,7 +58,7 @@ type RegisterResponse struct {
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
-	// FCMToken string `json:"fcm_token"`
+	FCMToken string `json:"fcm_token"`
 }
 
 type LoginResponse struct {
@@ -176,6 +180,11 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 	user, token, err := h.authUseCase.Register(r.Context(), email, password, role, name, birthDate, fileURL, phoneNumber, gender, address, bloodType, rhesus, fcmToken)
 
+	if errors.Is(err, usecase.ErrInvalidPhoneNumber) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -222,11 +231,12 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// err = h.authUseCase.ValidateFcmToken(r.Context(), req.Email, req.FCMToken)
-	// if err != nil {
-	// 	http.Error(w, err.Error(), http.StatusInternalServerError)
-	// 	return
-	// }
+	if req.FCMToken != "" {
+		err = h.authUseCase.ValidateFcmToken(r.Context(), req.Email, req.FCMToken)
+		if err != nil {
+			log.Printf("Failed to update fcm token for %s: %v", req.Email, err)
+		}
+	}
 
 	// Get user data
 	claims, err := h.jwtService.ValidateToken(token)
@@ -433,6 +443,9 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// koneksi chat yang dibuka dengan sesi ini tidak boleh tetap aktif setelah logout
+	h.webSocketHandler.Disconnect(userID)
+
 	// Clear cookie jika web
 	http.SetCookie(w, &http.Cookie{
 		Name:     "token",
@@ -448,3 +461,32 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		"message": "successfully logged out",
 	})
 }
+
+// @Summary Issue a websocket ticket
+// @Description Issue a single-use ticket for opening the chat websocket (/api/message?ticket=...) from clients that cannot send the Authorization header
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Router /api/ws/ticket [post]
+func (h *AuthHandler) WebSocketTicket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ticket, err := h.authUseCase.IssueWebSocketTicket(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ticket":     ticket.Token,
+		"expires_at": ticket.ExpiresAt,
+	})
+}
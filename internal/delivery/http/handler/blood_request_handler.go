@@ -0,0 +1,408 @@
+package handler
+
+import (
+	"backend/internal/usecase"
+	"backend/pkg/bloodcompat"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type BloodRequestHandler struct {
+	bloodRequestUseCase usecase.BloodRequestUseCase
+	escalationUseCase   usecase.EscalationUseCase
+}
+
+func NewBloodRequestHandler(bloodRequestUseCase usecase.BloodRequestUseCase, escalationUseCase usecase.EscalationUseCase) *BloodRequestHandler {
+	return &BloodRequestHandler{
+		bloodRequestUseCase: bloodRequestUseCase,
+		escalationUseCase:   escalationUseCase,
+	}
+}
+
+type BloodRequestRequest struct {
+	ID         uint       `json:"id"`
+	SearchName string     `json:"search_name"`
+	Location   string     `json:"location"`
+	FacilityID *uint      `json:"facility_id"`
+	Latitude   *float64   `json:"latitude"`
+	Longitude  *float64   `json:"longitude"`
+	BloodType  string     `json:"blood_type"`
+	Rhesus     string     `json:"rhesus"`
+	Total      int        `json:"total"`
+	Urgency    int        `json:"urgency"`
+	Deadline   *time.Time `json:"deadline"`
+}
+
+type BloodCompatibilityResponse struct {
+	Group      bloodcompat.Group   `json:"group"`
+	DonorsFor  []bloodcompat.Group `json:"can_receive_from"`
+	Recipients []bloodcompat.Group `json:"can_donate_to"`
+}
+
+func bloodRequestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, usecase.ErrBloodRequestNotFound), errors.Is(err, usecase.ErrFacilityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, usecase.ErrBloodRequestForbidden), errors.Is(err, usecase.ErrNotPencari), errors.Is(err, usecase.ErrNotPendonor):
+		return http.StatusForbidden
+	case errors.Is(err, usecase.ErrInvalidBloodRequest), errors.Is(err, usecase.ErrBloodTypeNotSet), errors.Is(err, usecase.ErrInvalidCursor):
+		return http.StatusBadRequest
+	case errors.Is(err, usecase.ErrBloodRequestClosed), errors.Is(err, usecase.ErrInvalidTransition):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeBloodRequestError(w http.ResponseWriter, err error) {
+	status := bloodRequestErrorStatus(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = "Internal Server Error"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary Create blood request
+// @Description Create a new blood request owned by the authenticated pencari
+// @Tags Blood Request
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BloodRequestRequest true "Blood request"
+// @Success 201 {object} entity.BloodRequest
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/blood-request [post]
+func (h *BloodRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BloodRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	request, err := h.bloodRequestUseCase.Create(r.Context(), userID, req.SearchName, req.Location, req.FacilityID, req.Latitude, req.Longitude, req.BloodType, req.Rhesus, req.Total, req.Urgency, req.Deadline)
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(request)
+}
+
+// @Summary Get blood request
+// @Description Get a blood request by id
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Blood Request ID"
+// @Success 200 {object} entity.BloodRequest
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/blood-request [get]
+func (h *BloodRequestHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.bloodRequestUseCase.GetByID(r.Context(), uint(id))
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// @Summary List my blood requests
+// @Description List blood requests created by the authenticated user
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.BloodRequest
+// @Failure 500 {object} map[string]string
+// @Router /api/blood-requests [get]
+func (h *BloodRequestHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	requests, err := h.bloodRequestUseCase.GetByUserID(r.Context(), userID)
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// @Summary Donor feed
+// @Description List open blood requests the authenticated pendonor can help with, sorted by urgency then distance
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Param cursor query string false "Cursor from previous page"
+// @Param limit query int false "Page size" default(20)
+// @Success 200 {object} entity.BloodRequestFeed
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/blood-requests/feed [get]
+func (h *BloodRequestHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var limit int
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	feed, err := h.bloodRequestUseCase.Feed(r.Context(), userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feed)
+}
+
+// @Summary Update blood request
+// @Description Update a blood request owned by the authenticated user
+// @Tags Blood Request
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BloodRequestRequest true "Blood request"
+// @Success 200 {object} entity.BloodRequest
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/blood-request [put]
+func (h *BloodRequestHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req BloodRequestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	request, err := h.bloodRequestUseCase.Update(r.Context(), userID, req.ID, req.SearchName, req.Location, req.FacilityID, req.Latitude, req.Longitude, req.BloodType, req.Rhesus, req.Total, req.Urgency, req.Deadline)
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(request)
+}
+
+// @Summary Cancel blood request
+// @Description Cancel a blood request owned by the authenticated user
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Blood Request ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/blood-request/cancel [post]
+func (h *BloodRequestHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.bloodRequestUseCase.Cancel(r.Context(), userID, uint(id))
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Blood request cancelled"})
+}
+
+// @Summary Fulfill blood request
+// @Description Mark a blood request owned by the authenticated user as fulfilled
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Blood Request ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/blood-request/fulfill [post]
+func (h *BloodRequestHandler) Fulfill(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.bloodRequestUseCase.Fulfill(r.Context(), userID, uint(id))
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Blood request fulfilled"})
+}
+
+// @Summary Find nearby donors
+// @Description Find eligible, compatible donors within radius_km of a blood request owned by the authenticated user
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Blood Request ID"
+// @Param radius_km query number false "Radius in km" default(25)
+// @Success 200 {array} entity.NearbyDonor
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/blood-request/donors [get]
+func (h *BloodRequestHandler) GetNearbyDonors(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var radiusKm float64
+	if radiusStr := r.URL.Query().Get("radius_km"); radiusStr != "" {
+		radiusKm, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil {
+			http.Error(w, "Invalid radius", http.StatusBadRequest)
+			return
+		}
+	}
+
+	donors, err := h.bloodRequestUseCase.FindNearbyDonors(r.Context(), userID, uint(id), radiusKm)
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(donors)
+}
+
+// @Summary Blood request escalations
+// @Description List escalation steps taken for a blood request owned by the authenticated user
+// @Tags Blood Request
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Blood Request ID"
+// @Success 200 {array} entity.BloodRequestEscalation
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/blood-request/escalations [get]
+func (h *BloodRequestHandler) GetEscalations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	escalations, err := h.escalationUseCase.GetByBloodRequestID(r.Context(), userID, uint(id))
+	if err != nil {
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(escalations)
+}
+
+// @Summary Blood compatibility
+// @Description Get donor and recipient groups compatible with the given blood type and rhesus
+// @Tags Blood Request
+// @Produce json
+// @Param blood_type query string true "Blood Type" default(A)
+// @Param rhesus query string true "Rhesus" default(positive)
+// @Success 200 {object} BloodCompatibilityResponse
+// @Failure 400 {object} map[string]string
+// @Router /api/blood-compatibility [get]
+func (h *BloodRequestHandler) GetCompatibility(w http.ResponseWriter, r *http.Request) {
+	group := bloodcompat.Group{
+		BloodType: r.URL.Query().Get("blood_type"),
+		Rhesus:    r.URL.Query().Get("rhesus"),
+	}
+
+	if !bloodcompat.IsValid(group) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid blood type or rhesus"})
+		return
+	}
+
+	response := BloodCompatibilityResponse{
+		Group:      group,
+		DonorsFor:  bloodcompat.DonorsFor(group),
+		Recipients: bloodcompat.RecipientsOf(group),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"backend/internal/usecase"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+type ConversationHandler struct {
+	messageUseCase usecase.MessageUseCase
+}
+
+func NewConversationHandler(messageUseCase usecase.MessageUseCase) *ConversationHandler {
+	return &ConversationHandler{
+		messageUseCase: messageUseCase,
+	}
+}
+
+// @Summary List conversations
+// @Description Chat peers of the authenticated user with the last message and unread count, most recent first. Changes are also pushed on the chat socket as conversation_update events.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param cursor query int false "next_cursor from previous page"
+// @Param limit query int false "Page size" default(20)
+// @Success 200 {object} entity.ConversationPage
+// @Failure 400 {object} map[string]string
+// @Router /api/conversations [get]
+func (h *ConversationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var cursor uint64
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		var err error
+		cursor, err = strconv.ParseUint(cursorStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var limit int
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := h.messageUseCase.ListConversations(r.Context(), userID, uint(cursor), limit)
+	if err != nil {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type DeviceTokenHandler struct {
+	deviceTokenUseCase usecase.DeviceTokenUseCase
+}
+
+func NewDeviceTokenHandler(deviceTokenUseCase usecase.DeviceTokenUseCase) *DeviceTokenHandler {
+	return &DeviceTokenHandler{
+		deviceTokenUseCase: deviceTokenUseCase,
+	}
+}
+
+type DeviceTokenRequest struct {
+	Token      string `json:"token"`
+	Platform   string `json:"platform"`
+	AppVersion string `json:"app_version"`
+}
+
+func writeDeviceTokenError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Internal Server Error"
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidDeviceToken):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, usecase.ErrDeviceTokenNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary List registered devices
+// @Description List push notification devices registered by the authenticated user
+// @Tags Device
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.DeviceToken
+// @Router /api/device-tokens [get]
+func (h *DeviceTokenHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	devices, err := h.deviceTokenUseCase.GetByUserID(r.Context(), userID)
+	if err != nil {
+		writeDeviceTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(devices)
+}
+
+// @Summary Register device token
+// @Description Register or refresh an FCM token for one of the authenticated user's devices
+// @Tags Device
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body DeviceTokenRequest true "Device token (platform: android, ios, web)"
+// @Success 200 {object} entity.DeviceToken
+// @Failure 400 {object} map[string]string
+// @Router /api/device-token [post]
+func (h *DeviceTokenHandler) Register(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req DeviceTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	device, err := h.deviceTokenUseCase.Register(r.Context(), userID, req.Token, entity.DevicePlatform(req.Platform), req.AppVersion)
+	if err != nil {
+		writeDeviceTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(device)
+}
+
+// @Summary Unregister device token
+// @Description Remove an FCM token, e.g. when the user logs out on that device
+// @Tags Device
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body DeviceTokenRequest true "Device token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/device-token [delete]
+func (h *DeviceTokenHandler) Unregister(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req DeviceTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.deviceTokenUseCase.Unregister(r.Context(), userID, req.Token); err != nil {
+		writeDeviceTokenError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Device token removed"})
+}
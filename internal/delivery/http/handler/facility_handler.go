@@ -0,0 +1,412 @@
+package handler
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type FacilityHandler struct {
+	facilityUseCase      usecase.FacilityUseCase
+	facilityStockUseCase usecase.FacilityStockUseCase
+}
+
+func NewFacilityHandler(facilityUseCase usecase.FacilityUseCase, facilityStockUseCase usecase.FacilityStockUseCase) *FacilityHandler {
+	return &FacilityHandler{
+		facilityUseCase:      facilityUseCase,
+		facilityStockUseCase: facilityStockUseCase,
+	}
+}
+
+type FacilityRequest struct {
+	ID           uint                `json:"id"`
+	Name         string              `json:"name"`
+	Type         entity.FacilityType `json:"type"`
+	Address      string              `json:"address"`
+	Latitude     float64             `json:"latitude"`
+	Longitude    float64             `json:"longitude"`
+	OpeningHours string              `json:"opening_hours"`
+	Contact      string              `json:"contact"`
+	BloodTypes   []string            `json:"blood_types"`
+}
+
+type FacilityPartnerRequest struct {
+	FacilityID uint `json:"facility_id"`
+	UserID     uint `json:"user_id"`
+}
+
+type StockReportRequest struct {
+	FacilityID uint                `json:"facility_id"`
+	Stocks     []entity.StockLevel `json:"stocks"`
+}
+
+func (req FacilityRequest) toEntity() *entity.Facility {
+	return &entity.Facility{
+		ID:           req.ID,
+		Name:         req.Name,
+		Type:         req.Type,
+		Address:      req.Address,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		OpeningHours: req.OpeningHours,
+		Contact:      req.Contact,
+		BloodTypes:   req.BloodTypes,
+	}
+}
+
+func writeFacilityError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Internal Server Error"
+
+	switch {
+	case errors.Is(err, usecase.ErrFacilityNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, usecase.ErrNotAdmin), errors.Is(err, usecase.ErrNotFacilityPartner):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, usecase.ErrInvalidFacility):
+		status, message = http.StatusBadRequest, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary Create facility
+// @Description Create a blood donation facility (admin only)
+// @Tags Facility
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FacilityRequest true "Facility"
+// @Success 201 {object} entity.Facility
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/facility [post]
+func (h *FacilityHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req FacilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	facility := req.toEntity()
+	if err := h.facilityUseCase.Create(r.Context(), userID, facility); err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(facility)
+}
+
+// @Summary Get facility
+// @Description Get a blood donation facility by id
+// @Tags Facility
+// @Produce json
+// @Param id query int true "Facility ID"
+// @Success 200 {object} entity.Facility
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility [get]
+func (h *FacilityHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	facility, err := h.facilityUseCase.GetByID(r.Context(), uint(id))
+	if err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(facility)
+}
+
+// @Summary List facilities
+// @Description List blood donation facilities, optionally filtered by type
+// @Tags Facility
+// @Produce json
+// @Param type query string false "Facility type (pmi_udd, hospital, mobile_unit)"
+// @Success 200 {array} entity.Facility
+// @Failure 400 {object} map[string]string
+// @Router /api/facilities [get]
+func (h *FacilityHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	facilities, err := h.facilityUseCase.GetAll(r.Context(), entity.FacilityType(r.URL.Query().Get("type")))
+	if err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(facilities)
+}
+
+// @Summary Update facility
+// @Description Update a blood donation facility (admin only)
+// @Tags Facility
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FacilityRequest true "Facility"
+// @Success 200 {object} entity.Facility
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility [put]
+func (h *FacilityHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req FacilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	facility := req.toEntity()
+	if err := h.facilityUseCase.Update(r.Context(), userID, facility); err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(facility)
+}
+
+// @Summary Delete facility
+// @Description Delete a blood donation facility (admin only)
+// @Tags Facility
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Facility ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility [delete]
+func (h *FacilityHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.facilityUseCase.Delete(r.Context(), userID, uint(id)); err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Facility deleted"})
+}
+
+// @Summary Nearest facilities
+// @Description Find blood donation facilities nearest to the given coordinates
+// @Tags Facility
+// @Produce json
+// @Param latitude query number true "Latitude"
+// @Param longitude query number true "Longitude"
+// @Param radius_km query number false "Radius in km" default(50)
+// @Param limit query int false "Maximum results" default(10)
+// @Success 200 {array} entity.NearbyFacility
+// @Failure 400 {object} map[string]string
+// @Router /api/facilities/nearest [get]
+func (h *FacilityHandler) GetNearest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	latitude, err := strconv.ParseFloat(query.Get("latitude"), 64)
+	if err != nil {
+		http.Error(w, "Invalid latitude", http.StatusBadRequest)
+		return
+	}
+
+	longitude, err := strconv.ParseFloat(query.Get("longitude"), 64)
+	if err != nil {
+		http.Error(w, "Invalid longitude", http.StatusBadRequest)
+		return
+	}
+
+	var radiusKm float64
+	if radiusStr := query.Get("radius_km"); radiusStr != "" {
+		radiusKm, err = strconv.ParseFloat(radiusStr, 64)
+		if err != nil {
+			http.Error(w, "Invalid radius", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var limit int
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	facilities, err := h.facilityUseCase.FindNearest(r.Context(), latitude, longitude, radiusKm, limit)
+	if err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(facilities)
+}
+
+// @Summary Add facility partner
+// @Description Allow a user to report stock for a facility (admin only)
+// @Tags Facility
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body FacilityPartnerRequest true "Facility partner"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility/partner [post]
+func (h *FacilityHandler) AddPartner(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req FacilityPartnerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FacilityID == 0 || req.UserID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	if err := h.facilityUseCase.AddPartner(r.Context(), userID, req.FacilityID, req.UserID); err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Facility partner added"})
+}
+
+// @Summary Report facility stock
+// @Description Report current blood stock per blood type and rhesus (facility partner or admin)
+// @Tags Facility
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body StockReportRequest true "Stock report"
+// @Success 200 {array} entity.FacilityStock
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility/stock [put]
+func (h *FacilityHandler) ReportStock(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req StockReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FacilityID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	stocks, err := h.facilityStockUseCase.ReportStock(r.Context(), userID, req.FacilityID, req.Stocks)
+	if err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocks)
+}
+
+// @Summary Get facility stock
+// @Description Get current blood stock of a facility
+// @Tags Facility
+// @Produce json
+// @Param id query int true "Facility ID"
+// @Success 200 {array} entity.FacilityStock
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility/stock [get]
+func (h *FacilityHandler) GetStocks(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	stocks, err := h.facilityStockUseCase.GetStocks(r.Context(), uint(id))
+	if err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stocks)
+}
+
+// @Summary Get facility stock history
+// @Description Get reported stock history of a facility, newest first
+// @Tags Facility
+// @Produce json
+// @Param id query int true "Facility ID"
+// @Param limit query int false "Maximum results" default(50)
+// @Success 200 {array} entity.FacilityStockHistory
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/facility/stock/history [get]
+func (h *FacilityHandler) GetStockHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var limit int
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	histories, err := h.facilityStockUseCase.GetHistory(r.Context(), uint(id), limit)
+	if err != nil {
+		writeFacilityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(histories)
+}
@@ -4,44 +4,142 @@ import (
 	"backend/internal/entity"
 	"backend/internal/usecase"
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
 type FcmHandler struct {
-	fcmUseCase usecase.FCMUseCase
+	broadcastUseCase usecase.BroadcastUseCase
 }
 
-func NewFcmHandler(fcmUseCase usecase.FCMUseCase) *FcmHandler {
+func NewFcmHandler(broadcastUseCase usecase.BroadcastUseCase) *FcmHandler {
 	return &FcmHandler{
-		fcmUseCase: fcmUseCase,
+		broadcastUseCase: broadcastUseCase,
 	}
 }
 
+func writeBroadcastError(w http.ResponseWriter, err error) {
+	status := bloodRequestErrorStatus(err)
+
+	switch {
+	case errors.Is(err, usecase.ErrBroadcastDisabled):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, usecase.ErrBroadcastRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, usecase.ErrDuplicateBroadcast):
+		status = http.StatusConflict
+	case errors.Is(err, usecase.ErrNotAdmin):
+		status = http.StatusForbidden
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = "Internal Server Error"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary Broadcast blood request
+// @Description Notify compatible donors about an open blood request owned by the authenticated pencari. The notification text is generated from the request. Broadcasts are rate limited per user and per request.
+// @Tags Broadcast
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.RequestFcm true "Blood request to broadcast"
+// @Success 202 {object} entity.BloodBroadcast
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /api/broadcast [post]
 func (f *FcmHandler) SendFCM(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req entity.RequestFcm
-	err := json.NewDecoder(r.Body).Decode(&req)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// broadcast dikirim oleh outbox worker, client hanya menunggu validasi dan antrean
+	broadcast, err := f.broadcastUseCase.Queue(r.Context(), userID, req.BloodRequestID)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": "Invalid request body",
-		})
+		writeBroadcastError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(broadcast)
+}
+
+// @Summary Get broadcast kill switch
+// @Tags Broadcast
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.FeatureFlag
+// @Failure 403 {object} map[string]string
+// @Router /api/admin/broadcast/kill-switch [get]
+func (f *FcmHandler) GetKillSwitch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	flag, err := f.broadcastUseCase.GetKillSwitch(r.Context(), userID)
+	if err != nil {
+		writeBroadcastError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
+
+type KillSwitchRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+// @Summary Toggle broadcast kill switch
+// @Description When enabled, new broadcasts are rejected and queued broadcasts are not delivered (admin only)
+// @Tags Broadcast
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body KillSwitchRequest true "Kill switch state"
+// @Success 200 {object} entity.FeatureFlag
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/admin/broadcast/kill-switch [put]
+func (f *FcmHandler) SetKillSwitch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req KillSwitchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = f.fcmUseCase.SendFCMV1(r.Context(), req.UserID, req.BloodType, req.Title, req.Body)
+	flag, err := f.broadcastUseCase.SetKillSwitch(r.Context(), userID, req.Enabled)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{
-			"error": err.Error(),
-		})
+		writeBroadcastError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Success",
-	})
+	json.NewEncoder(w).Encode(flag)
 }
@@ -4,6 +4,7 @@ import (
 	"backend/internal/infrastructure/storage"
 	"backend/internal/usecase"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -65,13 +66,21 @@ type HistoryResponse struct {
 // @Accept multipart/form-data
 // @Produce json
 // @Param image_donor formData file true "Image Donor"
-// @Param user_id formData string true "User ID"
 // @Param blood_request_id formData string true "Blood Request ID"
+// @Param facility_id formData string false "Facility ID"
+// @Security BearerAuth
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /api/history [post]
 func (h *HistoryHandler) PostHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	err := r.ParseMultipartForm(10 << 20)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -212,20 +221,7 @@ func (h *HistoryHandler) PostHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var historyRequest HistoryRequest
-	err = json.NewDecoder(r.Body).Decode(&historyRequest)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
-		return
-	}
-
-	userID, err := strconv.ParseUint(r.FormValue("user_id"), 10, 64)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid user ID"})
-		return
-	}
+	// body multipart sudah dibaca oleh ParseMultipartForm, data diambil dari form value
 	bloodRequestID, err := strconv.ParseUint(r.FormValue("blood_request_id"), 10, 64)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -233,8 +229,21 @@ func (h *HistoryHandler) PostHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// facility_id opsional: tempat donor dilakukan
+	var facilityID *uint
+	if facilityStr := r.FormValue("facility_id"); facilityStr != "" {
+		id, err := strconv.ParseUint(facilityStr, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"message": "Invalid facility ID"})
+			return
+		}
+		fid := uint(id)
+		facilityID = &fid
+	}
+
 	now := time.Now()
-	user, err := h.authUseCase.GetUserByID(r.Context(), uint(userID))
+	user, err := h.authUseCase.GetUserByID(r.Context(), userID)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Internal Server Error"})
@@ -252,27 +261,18 @@ func (h *HistoryHandler) PostHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.historyUseCase.AddHistory(r.Context(), uint(userID), uint(bloodRequestID), fileInfo.URL, nextDonation)
+	// total donasi, coin dan sisa kebutuhan blood request ikut diperbarui di usecase
+	err = h.historyUseCase.AddHistory(r.Context(), userID, uint(bloodRequestID), facilityID, fileInfo.URL, nextDonation)
 	if err != nil {
+		if errors.Is(err, usecase.ErrBloodRequestNotFound) || errors.Is(err, usecase.ErrFacilityNotFound) {
+			writeBloodRequestError(w, err)
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"message": "Failed to create history"})
 		return
 	}
 
-	total := user.TotalDonation + 1
-	coin := user.Coin + 10
-	err = h.authUseCase.UpdateCountDonation(r.Context(), uint(userID), total)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Failed to update count donation"})
-	}
-
-	err = h.authUseCase.UpdateCoinTotal(r.Context(), uint(userID), coin)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Failed to update coin"})
-	}
-
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "History created"})
 }
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type MessagingHandler struct {
+	messagingUseCase usecase.MessagingUseCase
+	webhookSecret    string
+}
+
+func NewMessagingHandler(messagingUseCase usecase.MessagingUseCase, webhookSecret string) *MessagingHandler {
+	return &MessagingHandler{
+		messagingUseCase: messagingUseCase,
+		webhookSecret:    webhookSecret,
+	}
+}
+
+type MessagingOptInRequest struct {
+	Channel string `json:"channel"`
+}
+
+type DeliveryStatusRequest struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+func writeMessagingError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Internal Server Error"
+
+	switch {
+	case errors.Is(err, usecase.ErrInvalidPhoneNumber),
+		errors.Is(err, usecase.ErrInvalidMessagingChannel),
+		errors.Is(err, usecase.ErrInvalidTextMessageStatus):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, usecase.ErrTextMessageNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary Get SMS / WhatsApp preference
+// @Tags Messaging
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.MessagingPreference
+// @Router /api/messaging/preference [get]
+func (h *MessagingHandler) GetPreference(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preference, err := h.messagingUseCase.GetPreference(r.Context(), userID)
+	if err != nil {
+		writeMessagingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preference)
+}
+
+// @Summary Opt in to SMS / WhatsApp
+// @Description Receive urgent blood requests by SMS or WhatsApp when push notifications cannot reach the user. Requires a valid phone number on the profile.
+// @Tags Messaging
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MessagingOptInRequest true "Channel: sms or whatsapp"
+// @Success 200 {object} entity.MessagingPreference
+// @Failure 400 {object} map[string]string
+// @Router /api/messaging/opt-in [post]
+func (h *MessagingHandler) OptIn(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req MessagingOptInRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	preference, err := h.messagingUseCase.OptIn(r.Context(), userID, req.Channel)
+	if err != nil {
+		writeMessagingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preference)
+}
+
+// @Summary Opt out of SMS / WhatsApp
+// @Tags Messaging
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.MessagingPreference
+// @Router /api/messaging/opt-out [post]
+func (h *MessagingHandler) OptOut(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preference, err := h.messagingUseCase.OptOut(r.Context(), userID)
+	if err != nil {
+		writeMessagingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preference)
+}
+
+// @Summary Messaging delivery status callback
+// @Description Called by the SMS / WhatsApp provider. Authenticated with the X-Webhook-Secret header.
+// @Tags Messaging
+// @Accept json
+// @Produce json
+// @Param X-Webhook-Secret header string true "Shared webhook secret"
+// @Param request body DeliveryStatusRequest true "Provider message id and status (queued, sent, delivered, failed)"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/webhooks/messaging/status [post]
+func (h *MessagingHandler) DeliveryStatus(w http.ResponseWriter, r *http.Request) {
+	secret := r.Header.Get("X-Webhook-Secret")
+	if h.webhookSecret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.webhookSecret)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req DeliveryStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.messagingUseCase.UpdateDeliveryStatus(r.Context(), req.ID, entity.TextMessageStatus(req.Status), req.Error); err != nil {
+		writeMessagingError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Status updated"})
+}
@@ -0,0 +1,225 @@
+package handler
+
+import (
+	"backend/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type NotificationHandler struct {
+	notificationUseCase usecase.NotificationUseCase
+	preferenceUseCase   usecase.NotificationPreferenceUseCase
+}
+
+func NewNotificationHandler(notificationUseCase usecase.NotificationUseCase, preferenceUseCase usecase.NotificationPreferenceUseCase) *NotificationHandler {
+	return &NotificationHandler{
+		notificationUseCase: notificationUseCase,
+		preferenceUseCase:   preferenceUseCase,
+	}
+}
+
+func writeNotificationError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Internal Server Error"
+
+	switch {
+	case errors.Is(err, usecase.ErrNotificationNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, usecase.ErrInvalidNotificationPreference):
+		status, message = http.StatusBadRequest, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary List notifications
+// @Description List notifications in the authenticated user's inbox, newest first
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Param cursor query int false "next_cursor from previous page"
+// @Param limit query int false "Page size" default(20)
+// @Success 200 {object} entity.NotificationPage
+// @Failure 400 {object} map[string]string
+// @Router /api/notifications [get]
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var cursor uint64
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		var err error
+		cursor, err = strconv.ParseUint(cursorStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var limit int
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := h.notificationUseCase.List(r.Context(), userID, uint(cursor), limit)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// @Summary Mark notification as read
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Notification ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/notification/read [put]
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.notificationUseCase.MarkRead(r.Context(), userID, uint(id)); err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Notification marked as read"})
+}
+
+// @Summary Mark all notifications as read
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Router /api/notifications/read [put]
+func (h *NotificationHandler) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.notificationUseCase.MarkAllRead(r.Context(), userID); err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "All notifications marked as read"})
+}
+
+// @Summary Unread notification count
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]int
+// @Router /api/notifications/unread-count [get]
+func (h *NotificationHandler) UnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	count, err := h.notificationUseCase.UnreadCount(r.Context(), userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int{"unread": count})
+}
+
+// @Summary Get notification preferences
+// @Description Categories, daily push limit and quiet hours of the authenticated user
+// @Tags Notification
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} entity.NotificationPreference
+// @Router /api/notification-preferences [get]
+func (h *NotificationHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preference, err := h.preferenceUseCase.Get(r.Context(), userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preference)
+}
+
+// @Summary Update notification preferences
+// @Description Fields that are omitted keep their current value. Quiet hours use HH:MM in the given IANA timezone; send empty strings to disable them. Urgent requests bypass quiet hours.
+// @Tags Notification
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body entity.NotificationPreference true "Notification preferences"
+// @Success 200 {object} entity.NotificationPreference
+// @Failure 400 {object} map[string]string
+// @Router /api/notification-preferences [put]
+func (h *NotificationHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	preference, err := h.preferenceUseCase.Get(r.Context(), userID)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	// body ditimpa ke preferensi saat ini sehingga field yang tidak dikirim tidak berubah
+	if err := json.NewDecoder(r.Body).Decode(preference); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	preference, err = h.preferenceUseCase.Update(r.Context(), userID, preference)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preference)
+}
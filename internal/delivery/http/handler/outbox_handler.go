@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"backend/internal/entity"
+	"backend/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type OutboxHandler struct {
+	outboxUseCase usecase.OutboxUseCase
+}
+
+func NewOutboxHandler(outboxUseCase usecase.OutboxUseCase) *OutboxHandler {
+	return &OutboxHandler{
+		outboxUseCase: outboxUseCase,
+	}
+}
+
+func writeOutboxError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Internal Server Error"
+
+	switch {
+	case errors.Is(err, usecase.ErrOutboxNotFound):
+		status, message = http.StatusNotFound, err.Error()
+	case errors.Is(err, usecase.ErrNotAdmin):
+		status, message = http.StatusForbidden, err.Error()
+	case errors.Is(err, usecase.ErrInvalidOutboxStatus):
+		status, message = http.StatusBadRequest, err.Error()
+	case errors.Is(err, usecase.ErrOutboxNotReplayable):
+		status, message = http.StatusConflict, err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
+
+// @Summary List outbox messages
+// @Description Inspect outbound notifications by delivery status (admin only)
+// @Tags Outbox
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, sent or dead" default(dead)
+// @Param limit query int false "Maximum results" default(50)
+// @Success 200 {array} entity.OutboxMessage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api/admin/outbox [get]
+func (h *OutboxHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+
+	var limit int
+	if limitStr := query.Get("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	messages, err := h.outboxUseCase.GetMessages(r.Context(), userID, entity.OutboxStatus(query.Get("status")), limit)
+	if err != nil {
+		writeOutboxError(w, err)
+		return
+	}
+
+	if messages == nil {
+		messages = []*entity.OutboxMessage{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// @Summary Replay outbox message
+// @Description Requeue a dead outbound notification with a fresh set of attempts (admin only)
+// @Tags Outbox
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Outbox message ID"
+// @Success 200 {object} entity.OutboxMessage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/admin/outbox/replay [post]
+func (h *OutboxHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	message, err := h.outboxUseCase.Replay(r.Context(), userID, uint(id))
+	if err != nil {
+		writeOutboxError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(message)
+}
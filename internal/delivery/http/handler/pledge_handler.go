@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"backend/internal/usecase"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type PledgeHandler struct {
+	pledgeUseCase    usecase.PledgeUseCase
+	webSocketHandler *WebSocketHandler
+}
+
+func NewPledgeHandler(pledgeUseCase usecase.PledgeUseCase, webSocketHandler *WebSocketHandler) *PledgeHandler {
+	return &PledgeHandler{
+		pledgeUseCase:    pledgeUseCase,
+		webSocketHandler: webSocketHandler,
+	}
+}
+
+type PledgeRequest struct {
+	BloodRequestID uint `json:"blood_request_id"`
+}
+
+func writePledgeError(w http.ResponseWriter, err error) {
+	var status int
+	switch {
+	case errors.Is(err, usecase.ErrPledgeNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, usecase.ErrNotPendonor), errors.Is(err, usecase.ErrOwnBloodRequest):
+		status = http.StatusForbidden
+	case errors.Is(err, usecase.ErrBloodIncompatible):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, usecase.ErrPledgeExists), errors.Is(err, usecase.ErrInvalidPledgeStatus), errors.Is(err, usecase.ErrPledgeLimitReached):
+		status = http.StatusConflict
+	default:
+		writeBloodRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": err.Error()})
+}
+
+// @Summary Pledge to a blood request
+// @Description Pendonor pledges ("Saya bersedia membantu") to a blood request
+// @Tags Pledge
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PledgeRequest true "Pledge request"
+// @Success 201 {object} entity.Pledge
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/pledge [post]
+func (h *PledgeHandler) Pledge(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PledgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.BloodRequestID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Invalid request body"})
+		return
+	}
+
+	pledge, err := h.pledgeUseCase.Pledge(r.Context(), userID, req.BloodRequestID)
+	if err != nil {
+		writePledgeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(pledge)
+}
+
+// @Summary Accept pledge
+// @Description Requester accepts a pledge, which opens a chat with the donor
+// @Tags Pledge
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Pledge ID"
+// @Success 200 {object} entity.Pledge
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/pledge/accept [post]
+func (h *PledgeHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	pledge, err := h.pledgeUseCase.Accept(r.Context(), userID, uint(id))
+	if err != nil {
+		writePledgeError(w, err)
+		return
+	}
+
+	// Kirim pesan pembuka ke pendonor jika sedang online
+	go func() {
+		h.webSocketHandler.pushConversationUpdate(userID, pledge.DonorID)
+		h.webSocketHandler.pushConversationUpdate(pledge.DonorID, userID)
+		h.webSocketHandler.sendUndeliveredMessages(pledge.DonorID)
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pledge)
+}
+
+// @Summary Decline pledge
+// @Description Requester declines a pledge
+// @Tags Pledge
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Pledge ID"
+// @Success 200 {object} entity.Pledge
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/pledge/decline [post]
+func (h *PledgeHandler) Decline(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	pledge, err := h.pledgeUseCase.Decline(r.Context(), userID, uint(id))
+	if err != nil {
+		writePledgeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pledge)
+}
+
+// @Summary Record no-show
+// @Description Requester records that an accepted donor did not show up
+// @Tags Pledge
+// @Produce json
+// @Security BearerAuth
+// @Param id query int true "Pledge ID"
+// @Success 200 {object} entity.Pledge
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /api/pledge/no-show [post]
+func (h *PledgeHandler) NoShow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	pledge, err := h.pledgeUseCase.MarkNoShow(r.Context(), userID, uint(id))
+	if err != nil {
+		writePledgeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pledge)
+}
+
+// @Summary List pledges of a blood request
+// @Description List pledges for a blood request owned by the authenticated user
+// @Tags Pledge
+// @Produce json
+// @Security BearerAuth
+// @Param blood_request_id query int true "Blood Request ID"
+// @Success 200 {array} entity.Pledge
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api/pledges [get]
+func (h *PledgeHandler) GetByBloodRequest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.URL.Query().Get("blood_request_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid blood request ID", http.StatusBadRequest)
+		return
+	}
+
+	pledges, err := h.pledgeUseCase.GetByBloodRequestID(r.Context(), userID, uint(id))
+	if err != nil {
+		writePledgeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pledges)
+}
+
+// @Summary List my pledges
+// @Description List pledges made by the authenticated donor
+// @Tags Pledge
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} entity.Pledge
+// @Router /api/pledges/mine [get]
+func (h *PledgeHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	pledges, err := h.pledgeUseCase.GetByDonorID(r.Context(), userID)
+	if err != nil {
+		writePledgeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pledges)
+}
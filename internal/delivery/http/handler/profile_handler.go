@@ -10,6 +10,16 @@ type ProfileHandler struct {
 	profileUseCase usecase.ProfileUseCase
 }
 
+type UpdateLocationRequest struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type UpdateBloodTypeRequest struct {
+	BloodType string `json:"blood_type"`
+	Rhesus    string `json:"rhesus"`
+}
+
 func NewProfileHandler(profileUseCase usecase.ProfileUseCase) *ProfileHandler {
 	return &ProfileHandler{
 		profileUseCase: profileUseCase,
@@ -73,3 +83,55 @@ func (h *ProfileHandler) UpdateProfilePhoto(w http.ResponseWriter, r *http.Reque
 		"url":     photoURL,
 	})
 }
+
+func (h *ProfileHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	// Ambil user ID dari context (diset oleh middleware auth)
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateLocationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.profileUseCase.UpdateLocation(r.Context(), userID, req.Latitude, req.Longitude)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Location updated successfully",
+	})
+}
+
+func (h *ProfileHandler) UpdateBloodType(w http.ResponseWriter, r *http.Request) {
+	// Ambil user ID dari context (diset oleh middleware auth)
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req UpdateBloodTypeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.profileUseCase.UpdateBloodType(r.Context(), userID, req.BloodType, req.Rhesus)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Blood type updated successfully",
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"backend/internal/entity"
 	"backend/internal/usecase"
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"sync"
@@ -13,18 +14,21 @@ import (
 )
 
 type WebSocketHandler struct {
-	clients        map[*websocket.Conn]uint // Maps connection to userID
-	connections    map[uint]*websocket.Conn // Maps userID to connection
-	broadcast      chan entity.MessageRequest
-	upgrader       websocket.Upgrader
-	messageUseCase usecase.MessageUseCase
-	mutex          sync.Mutex // For thread safety when manipulating maps
+	clients                 map[*websocket.Conn]uint        // Maps connection to userID
+	connections             map[uint]*websocket.Conn        // Maps userID to connection
+	writeLocks              map[*websocket.Conn]*sync.Mutex // Serializes writes per connection
+	broadcast               chan entity.MessageRequest
+	upgrader                websocket.Upgrader
+	messageUseCase          usecase.MessageUseCase
+	chatNotificationUseCase usecase.ChatNotificationUseCase
+	mutex                   sync.Mutex // For thread safety when manipulating maps
 }
 
-func NewWebSockerHandler(messageUseCase usecase.MessageUseCase) *WebSocketHandler {
+func NewWebSockerHandler(messageUseCase usecase.MessageUseCase, chatNotificationUseCase usecase.ChatNotificationUseCase) *WebSocketHandler {
 	return &WebSocketHandler{
 		clients:     make(map[*websocket.Conn]uint),
 		connections: make(map[uint]*websocket.Conn),
+		writeLocks:  make(map[*websocket.Conn]*sync.Mutex),
 		broadcast:   make(chan entity.MessageRequest),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
@@ -33,12 +37,21 @@ func NewWebSockerHandler(messageUseCase usecase.MessageUseCase) *WebSocketHandle
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 		},
-		messageUseCase: messageUseCase,
-		mutex:          sync.Mutex{},
+		messageUseCase:          messageUseCase,
+		chatNotificationUseCase: chatNotificationUseCase,
+		mutex:                   sync.Mutex{},
 	}
 }
 
+// HandleConnection harus dipasang di belakang AuthMiddleware.AuthenticateWebSocket;
+// identitas user diambil dari token, bukan dari pesan client
 func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(uint)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	ws, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Upgrade error:", err)
@@ -59,23 +72,14 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 		return nil
 	})
 
-	// Wait for authentication message containing user ID
-	var authMsg struct {
-		UserID uint `json:"user_id"`
-	}
-
-	if err := ws.ReadJSON(&authMsg); err != nil {
-		log.Println("Authentication error:", err)
-		ws.Close()
-		return
-	}
-
-	userID := authMsg.UserID
 	log.Printf("User %d connected via WebSocket", userID)
 
 	// Register this connection
 	h.registerConnection(ws, userID)
 
+	// User kembali online sebelum grace period habis, push untuk pesan offline dibatalkan
+	h.chatNotificationUseCase.CancelPending(userID)
+
 	// Send confirmation message
 	confirmMsg := map[string]interface{}{
 		"type":    "connected",
@@ -83,7 +87,7 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 		"time":    time.Now(),
 	}
 
-	if err := ws.WriteJSON(confirmMsg); err != nil {
+	if err := h.writeJSON(ws, confirmMsg); err != nil {
 		log.Println("Failed to send confirmation:", err)
 		h.cleanupConnection(ws)
 		ws.Close()
@@ -98,8 +102,8 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 
 	// Main message handling loop
 	for {
-		var msg entity.MessageRequest
-		err := ws.ReadJSON(&msg)
+		var event entity.ChatEvent
+		err := ws.ReadJSON(&event)
 
 		if err != nil {
 			log.Printf("Read error for user %d: %v", userID, err)
@@ -108,20 +112,236 @@ func (h *WebSocketHandler) HandleConnection(w http.ResponseWriter, r *http.Reque
 			break
 		}
 
-		// Validate the message has required fields
-		if msg.SenderID == 0 || msg.ReceiverID == 0 || msg.Content == "" {
-			log.Println("Invalid message format:", msg)
+		// Pengirim selalu user pemilik koneksi; sender_id milik user lain ditolak
+		if event.SenderID != 0 && event.SenderID != userID {
+			log.Printf("User %d tried to send an event as user %d", userID, event.SenderID)
+			h.writeError(ws, event.ClientMsgID, "sender_id does not match the authenticated user")
 			continue
 		}
+		event.SenderID = userID
 
-		// Set created time if not provided
-		if msg.CreatedAt.IsZero() {
-			msg.CreatedAt = time.Now()
+		if event.ReceiverID == 0 {
+			h.writeError(ws, event.ClientMsgID, "receiver_id is required")
+			continue
 		}
 
-		// Send to broadcast channel for processing
-		h.broadcast <- msg
-		log.Printf("Message from %d to %d queued for delivery", msg.SenderID, msg.ReceiverID)
+		switch event.Type {
+		case "", entity.ChatEventMessage:
+			h.queueMessage(ws, event)
+		case entity.ChatEventRead:
+			h.handleRead(ws, event)
+		case entity.ChatEventTypingStart, entity.ChatEventTypingStop:
+			h.relayTyping(event)
+		default:
+			h.writeError(ws, event.ClientMsgID, "unknown event type")
+		}
+	}
+}
+
+// queueMessage meneruskan pesan ke HandleMessages untuk disimpan dan dikirim
+func (h *WebSocketHandler) queueMessage(ws *websocket.Conn, event entity.ChatEvent) {
+	// Validate the message has required fields
+	if event.Content == "" {
+		log.Println("Invalid message format:", event)
+		h.writeError(ws, event.ClientMsgID, "content is required")
+		return
+	}
+
+	msg := entity.MessageRequest{
+		ClientMsgID: event.ClientMsgID,
+		SenderID:    event.SenderID,
+		ReceiverID:  event.ReceiverID,
+		Content:     event.Content,
+	}
+
+	// Send to broadcast channel for processing
+	h.broadcast <- msg
+	log.Printf("Message from %d to %d queued for delivery", msg.SenderID, msg.ReceiverID)
+}
+
+// handleRead menyimpan read receipt lalu memberi tahu pengirim pesan jika sedang online
+func (h *WebSocketHandler) handleRead(ws *websocket.Conn, event entity.ChatEvent) {
+	if event.MessageID == 0 {
+		h.writeError(ws, "", "message_id is required")
+		return
+	}
+
+	readAt, err := h.messageUseCase.MarkRead(context.Background(), event.SenderID, event.ReceiverID, event.MessageID)
+	if err != nil {
+		log.Printf("Failed to mark messages from %d as read by %d: %v", event.ReceiverID, event.SenderID, err)
+		h.writeError(ws, "", "failed to mark messages as read")
+		return
+	}
+
+	// tidak ada pesan baru yang dibaca, pengirim sudah pernah diberi tahu
+	if readAt == nil {
+		return
+	}
+
+	// jumlah pesan belum dibaca di daftar percakapan pembaca berubah
+	h.pushConversationUpdate(event.SenderID, event.ReceiverID)
+
+	conn, ok := h.connectionOf(event.ReceiverID)
+	if !ok {
+		return
+	}
+
+	receipt := entity.ChatEvent{
+		Type:       entity.ChatEventRead,
+		SenderID:   event.SenderID,
+		ReceiverID: event.ReceiverID,
+		MessageID:  event.MessageID,
+		ReadAt:     readAt,
+	}
+
+	if err := h.writeJSON(conn, receipt); err != nil {
+		log.Printf("Failed to send read receipt to user %d: %v", event.ReceiverID, err)
+	}
+}
+
+// pushConversationUpdate mengirim ringkasan terbaru percakapan userID dengan peerID jika
+// userID sedang online
+func (h *WebSocketHandler) pushConversationUpdate(userID, peerID uint) {
+	conn, ok := h.connectionOf(userID)
+	if !ok {
+		return
+	}
+
+	conversation, err := h.messageUseCase.GetConversation(context.Background(), userID, peerID)
+	if err != nil {
+		log.Printf("Failed to get conversation of user %d with %d: %v", userID, peerID, err)
+		return
+	}
+
+	if conversation == nil {
+		return
+	}
+
+	update := entity.ConversationUpdate{
+		Type:         entity.ChatEventConversationUpdate,
+		Conversation: conversation,
+	}
+
+	if err := h.writeJSON(conn, update); err != nil {
+		log.Printf("Failed to send conversation update to user %d: %v", userID, err)
+	}
+}
+
+// pushConversationUpdates mengirim ringkasan percakapan terbaru ke pengirim dan penerima message
+func (h *WebSocketHandler) pushConversationUpdates(message *entity.Message) {
+	h.pushConversationUpdate(message.SenderID, message.ReceiverID)
+	h.pushConversationUpdate(message.ReceiverID, message.SenderID)
+}
+
+// relayTyping meneruskan indikator mengetik ke lawan bicara yang sedang online. Event ini
+// tidak disimpan; jika penerima offline event dibuang.
+func (h *WebSocketHandler) relayTyping(event entity.ChatEvent) {
+	conn, ok := h.connectionOf(event.ReceiverID)
+	if !ok {
+		return
+	}
+
+	typing := entity.ChatEvent{
+		Type:       event.Type,
+		SenderID:   event.SenderID,
+		ReceiverID: event.ReceiverID,
+	}
+
+	if err := h.writeJSON(conn, typing); err != nil {
+		log.Printf("Failed to relay typing event to user %d: %v", event.ReceiverID, err)
+	}
+}
+
+// writeJSON menulis ke koneksi secara berurutan karena gorilla/websocket tidak mengizinkan
+// beberapa goroutine menulis ke koneksi yang sama sekaligus
+func (h *WebSocketHandler) writeJSON(ws *websocket.Conn, v interface{}) error {
+	h.mutex.Lock()
+	lock, ok := h.writeLocks[ws]
+	h.mutex.Unlock()
+
+	if ok {
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	return ws.WriteJSON(v)
+}
+
+// connectionOf mengembalikan koneksi aktif user jika sedang online
+func (h *WebSocketHandler) connectionOf(userID uint) (*websocket.Conn, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	conn, ok := h.connections[userID]
+	return conn, ok
+}
+
+// writeError memberi tahu client bahwa pesannya ditolak tanpa memutus koneksi
+func (h *WebSocketHandler) writeError(ws *websocket.Conn, clientMsgID, message string) {
+	errMsg := map[string]interface{}{
+		"type":    "error",
+		"message": message,
+		"time":    time.Now(),
+	}
+	if clientMsgID != "" {
+		errMsg["client_msg_id"] = clientMsgID
+	}
+
+	if err := h.writeJSON(ws, errMsg); err != nil {
+		log.Println("Failed to send error:", err)
+	}
+}
+
+// ack memberi tahu pengirim ID pesan dari server. duplicate berarti client_msg_id sudah
+// pernah diterima dan pesan tidak disimpan ulang.
+func (h *WebSocketHandler) ack(message *entity.Message, duplicate bool) {
+	conn, ok := h.connectionOf(message.SenderID)
+	if !ok {
+		return
+	}
+
+	ackMsg := map[string]interface{}{
+		"type":          "ack",
+		"message_id":    message.ID,
+		"client_msg_id": message.ClientMsgID,
+		"created_at":    message.CreatedAt,
+		"duplicate":     duplicate,
+	}
+
+	if err := h.writeJSON(conn, ackMsg); err != nil {
+		log.Printf("Failed to send ack to user %d: %v", message.SenderID, err)
+	}
+}
+
+// confirmDelivery memberi tahu pengirim bahwa pesan sudah diterima penerima
+func (h *WebSocketHandler) confirmDelivery(message *entity.Message) {
+	conn, ok := h.connectionOf(message.SenderID)
+	if !ok {
+		return
+	}
+
+	confirmation := map[string]interface{}{
+		"type":          "delivery_confirmation",
+		"message_id":    message.ID,
+		"client_msg_id": message.ClientMsgID,
+		"recipient_id":  message.ReceiverID,
+		"delivered_at":  time.Now(),
+	}
+
+	if err := h.writeJSON(conn, confirmation); err != nil {
+		log.Printf("Failed to send delivery confirmation to user %d: %v", message.SenderID, err)
+	}
+}
+
+// messageRequestOf mengubah pesan tersimpan menjadi pesan yang dikirim ke penerima
+func messageRequestOf(message *entity.Message) entity.MessageRequest {
+	return entity.MessageRequest{
+		Type:       entity.ChatEventMessage,
+		ID:         message.ID,
+		SenderID:   message.SenderID,
+		ReceiverID: message.ReceiverID,
+		Content:    message.Content,
+		CreatedAt:  message.CreatedAt,
 	}
 }
 
@@ -143,38 +363,30 @@ func (h *WebSocketHandler) sendUndeliveredMessages(userID uint) {
 
 	log.Printf("Found %d undelivered messages for user %d", len(messages), userID)
 
-	h.mutex.Lock()
-	conn, ok := h.connections[userID]
-	h.mutex.Unlock()
-
+	conn, ok := h.connectionOf(userID)
 	if !ok {
 		log.Printf("User %d connection not found", userID)
 		return
 	}
 
 	// Send each undelivered message
-	for _, msg := range messages {
-		messageReq := entity.MessageRequest{
-			SenderID:   msg.SenderID,
-			ReceiverID: msg.ReceiverID,
-			Content:    msg.Content,
-			CreatedAt:  msg.CreatedAt,
-		}
+	for i := range messages {
+		msg := &messages[i]
 
-		err := conn.WriteJSON(messageReq)
+		err := h.writeJSON(conn, messageRequestOf(msg))
 		if err != nil {
 			log.Printf("Failed to send undelivered message to user %d: %v", userID, err)
 			break
 		}
 
 		// Mark message as delivered
-		// Note: Ideally, you would have a message ID in your entity.Message struct
-		// This is a simplified example
 		err = h.messageUseCase.MarkMessageAsDelivered(ctx, msg.ID)
 		if err != nil {
 			log.Printf("Failed to mark message as delivered: %v", err)
 		}
 
+		h.confirmDelivery(msg)
+
 		log.Printf("Sent undelivered message from %d to %d", msg.SenderID, userID)
 	}
 }
@@ -190,6 +402,7 @@ func (h *WebSocketHandler) registerConnection(ws *websocket.Conn, userID uint) {
 
 		// Remove from clients map
 		delete(h.clients, oldConn)
+		delete(h.writeLocks, oldConn)
 
 		// Close the connection
 		oldConn.Close()
@@ -198,10 +411,39 @@ func (h *WebSocketHandler) registerConnection(ws *websocket.Conn, userID uint) {
 	// Register new connection
 	h.clients[ws] = userID
 	h.connections[userID] = ws
+	h.writeLocks[ws] = &sync.Mutex{}
 
 	log.Printf("User %d registered with new connection", userID)
 }
 
+// Disconnect menutup koneksi websocket user, misalnya setelah logout
+func (h *WebSocketHandler) Disconnect(userID uint) {
+	h.mutex.Lock()
+	conn, exists := h.connections[userID]
+	if exists {
+		delete(h.connections, userID)
+		delete(h.clients, conn)
+	}
+	lock := h.writeLocks[conn]
+	delete(h.writeLocks, conn)
+	h.mutex.Unlock()
+
+	if !exists {
+		return
+	}
+
+	if lock != nil {
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "logged out")
+	conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+	conn.Close()
+
+	log.Printf("User %d disconnected after logout", userID)
+}
+
 // Clean up a closed connection
 func (h *WebSocketHandler) cleanupConnection(ws *websocket.Conn) {
 	h.mutex.Lock()
@@ -216,6 +458,7 @@ func (h *WebSocketHandler) cleanupConnection(ws *websocket.Conn) {
 
 	// Remove from maps
 	delete(h.clients, ws)
+	delete(h.writeLocks, ws)
 
 	// Only delete from connections if this is still the active connection for the user
 	if conn, ok := h.connections[userID]; ok && conn == ws {
@@ -247,72 +490,65 @@ func (h *WebSocketHandler) HandleMessages() {
 		// Wait for a message from the broadcast channel
 		msg := <-h.broadcast
 
-		// Save to database
+		// Save to database; ID pesan diambil dari hasil insert
 		ctx := context.Background()
-		err := h.messageUseCase.SaveMessage(ctx, msg.SenderID, msg.ReceiverID, msg.Content)
+		message, created, err := h.messageUseCase.SaveMessage(ctx, msg.SenderID, msg.ReceiverID, msg.Content, msg.ClientMsgID)
 
 		if err != nil {
 			log.Printf("Failed to save message from %d to %d: %v", msg.SenderID, msg.ReceiverID, err)
+
+			if conn, ok := h.connectionOf(msg.SenderID); ok {
+				reason := "failed to save message"
+				if errors.Is(err, usecase.ErrInvalidClientMsgID) {
+					reason = err.Error()
+				}
+				h.writeError(conn, msg.ClientMsgID, reason)
+			}
 			continue
 		}
 
-		log.Printf("Message from %d to %d saved to database", msg.SenderID, msg.ReceiverID)
+		h.ack(message, !created)
 
-		// Get the message ID that was just saved
-		// Kita perlu mendapatkan ID pesan yang baru disimpan
-		message, err := h.messageUseCase.GetLastMessage(ctx, msg.SenderID, msg.ReceiverID)
-		if err != nil {
-			log.Printf("Failed to get message ID: %v", err)
+		// Pesan kiriman ulang sudah pernah diproses, tidak dikirim lagi ke penerima
+		if !created {
+			log.Printf("Duplicate message %s from %d ignored", msg.ClientMsgID, msg.SenderID)
 			continue
 		}
-		messageID := message.ID
+
+		log.Printf("Message %d from %d to %d saved to database", message.ID, message.SenderID, message.ReceiverID)
+
+		// pesan terakhir berubah di daftar percakapan kedua pihak; query-nya dijalankan di luar
+		// loop ini agar tidak menunda pesan berikutnya
+		go h.pushConversationUpdates(message)
 
 		// Try to deliver message to recipient if online
-		h.mutex.Lock()
-		conn, recipientOnline := h.connections[msg.ReceiverID]
-		h.mutex.Unlock()
+		conn, recipientOnline := h.connectionOf(message.ReceiverID)
 
 		if recipientOnline {
-			deliveryErr := conn.WriteJSON(msg)
+			deliveryErr := h.writeJSON(conn, messageRequestOf(message))
 
 			if deliveryErr != nil {
-				log.Printf("Failed to deliver message to user %d: %v", msg.ReceiverID, deliveryErr)
+				log.Printf("Failed to deliver message to user %d: %v", message.ReceiverID, deliveryErr)
 
 				// Clean up bad connection
-				h.mutex.Lock()
-				delete(h.clients, conn)
-				delete(h.connections, msg.ReceiverID)
-				h.mutex.Unlock()
-
+				h.cleanupConnection(conn)
 				conn.Close()
+
+				// pesan tetap tersimpan sebagai undelivered, penerima diberi tahu lewat push
+				h.chatNotificationUseCase.NotifyOffline(ctx, message.SenderID, message.ReceiverID, message.Content)
 			} else {
-				log.Printf("Message delivered to user %d", msg.ReceiverID)
+				log.Printf("Message %d delivered to user %d", message.ID, message.ReceiverID)
 
 				// Mark the message as delivered in the database
-				if err := h.messageUseCase.MarkMessageAsDelivered(ctx, messageID); err != nil {
+				if err := h.messageUseCase.MarkMessageAsDelivered(ctx, message.ID); err != nil {
 					log.Printf("Failed to mark message as delivered: %v", err)
 				}
 
-				// Send delivery confirmation to sender
-				h.mutex.Lock()
-				senderConn, senderOnline := h.connections[msg.SenderID]
-				h.mutex.Unlock()
-
-				if senderOnline {
-					confirmation := map[string]interface{}{
-						"type":         "delivery_confirmation",
-						"message_time": msg.CreatedAt,
-						"recipient_id": msg.ReceiverID,
-						"delivered_at": time.Now(),
-					}
-
-					if err := senderConn.WriteJSON(confirmation); err != nil {
-						log.Printf("Failed to send delivery confirmation to user %d: %v", msg.SenderID, err)
-					}
-				}
+				h.confirmDelivery(message)
 			}
 		} else {
-			log.Printf("Recipient %d is offline, message saved to database only", msg.ReceiverID)
+			log.Printf("Recipient %d is offline, message saved and push scheduled", message.ReceiverID)
+			h.chatNotificationUseCase.NotifyOffline(ctx, message.SenderID, message.ReceiverID, message.Content)
 		}
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 )
 
 type AuthMiddleware struct {
@@ -21,6 +22,30 @@ func NewAuthMiddleware(jwtService *jwt.JWTService, tokenRepo repository.TokenRep
 	}
 }
 
+// authenticate memvalidasi token dan blacklist, lalu menambahkan user ID ke context request
+func (m *AuthMiddleware) authenticate(w http.ResponseWriter, r *http.Request, token string, next http.Handler) {
+	claims, err := m.jwtService.ValidateToken(token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Tambahkan pengecekan blacklist
+	isBlacklisted, err := m.tokenRepo.Exists(r.Context(), token, entity.Blacklisted)
+	if err != nil {
+		http.Error(w, "authentication failed", http.StatusUnauthorized)
+		return
+	}
+	if isBlacklisted {
+		http.Error(w, "token has been revoked", http.StatusUnauthorized)
+		return
+	}
+
+	// Add user ID to request context
+	ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -35,26 +60,42 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		token := bearerToken[1]
-		claims, err := m.jwtService.ValidateToken(token)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+		m.authenticate(w, r, bearerToken[1], next)
+	})
+}
+
+// AuthenticateWebSocket sama seperti Authenticate, tetapi browser yang tidak bisa mengatur header
+// saat upgrade memakai tiket sekali pakai dari POST /api/ws/ticket: /api/message?ticket=<tiket>
+func (m *AuthMiddleware) AuthenticateWebSocket(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+			bearerToken := strings.Split(authHeader, " ")
+			if len(bearerToken) != 2 {
+				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+				return
+			}
+
+			m.authenticate(w, r, bearerToken[1], next)
+			return
+		}
+
+		ticket := r.URL.Query().Get("ticket")
+		if ticket == "" {
+			http.Error(w, "Authorization header or ticket is required", http.StatusUnauthorized)
 			return
 		}
 
-		// Tambahkan pengecekan blacklist
-		isBlacklisted, err := m.tokenRepo.Exists(r.Context(), token, entity.Blacklisted)
+		token, err := m.tokenRepo.Consume(r.Context(), ticket, entity.WebSocketTicket, time.Now())
 		if err != nil {
 			http.Error(w, "authentication failed", http.StatusUnauthorized)
 			return
 		}
-		if isBlacklisted {
-			http.Error(w, "token has been revoked", http.StatusUnauthorized)
+		if token == nil {
+			http.Error(w, "invalid or expired ticket", http.StatusUnauthorized)
 			return
 		}
 
-		// Add user ID to request context
-		ctx := context.WithValue(r.Context(), "user_id", claims.UserID)
+		ctx := context.WithValue(r.Context(), "user_id", token.UserID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
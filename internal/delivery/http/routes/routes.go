@@ -21,6 +21,14 @@ func SetupRoutes(
 	rewardHandler *handler.RewardHandler,
 	fcmHandler *handler.FcmHandler,
 	websockerHandler *handler.WebSocketHandler,
+	bloodRequestHandler *handler.BloodRequestHandler,
+	pledgeHandler *handler.PledgeHandler,
+	facilityHandler *handler.FacilityHandler,
+	notificationHandler *handler.NotificationHandler,
+	outboxHandler *handler.OutboxHandler,
+	deviceTokenHandler *handler.DeviceTokenHandler,
+	messagingHandler *handler.MessagingHandler,
+	conversationHandler *handler.ConversationHandler,
 
 ) {
 	// Public routes
@@ -42,12 +50,8 @@ func SetupRoutes(
 	// cek upload evidence image
 	router.HandleFunc("/api/upload-evidence", edivenceHandler.PostUploadEvidence).Methods("POST")
 
-	// cek history tanpa middleware
-	router.HandleFunc("/api/history", historyHandler.PostHistory).Methods("POST")
-
 	// cek history tanpa middleware
 	router.HandleFunc("/api/history", historyHandler.GetHistory).Methods("GET")
-	router.HandleFunc("/api/history", historyHandler.PostHistory).Methods("POST")
 	router.HandleFunc("/api/history/latest", historyHandler.GetLatestHistory).Methods("GET")
 	router.HandleFunc("/api/history/next", historyHandler.GetNextHistory).Methods("GET")
 
@@ -58,11 +62,21 @@ func SetupRoutes(
 	router.HandleFunc("/api/reward", rewardHandler.ClaimReward).Methods("POST")
 	router.HandleFunc("/api/reward/balance", rewardHandler.GetBalance).Methods("GET")
 
-	// Fcm tanpa middleware
-	router.HandleFunc("/api/broadcast", fcmHandler.SendFCM).Methods("POST")
+	// Kecocokan golongan darah
+	router.HandleFunc("/api/blood-compatibility", bloodRequestHandler.GetCompatibility).Methods("GET")
+
+	// Direktori fasilitas donor darah
+	router.HandleFunc("/api/facilities", facilityHandler.GetAll).Methods("GET")
+	router.HandleFunc("/api/facilities/nearest", facilityHandler.GetNearest).Methods("GET")
+	router.HandleFunc("/api/facility", facilityHandler.GetByID).Methods("GET")
+	router.HandleFunc("/api/facility/stock", facilityHandler.GetStocks).Methods("GET")
+	router.HandleFunc("/api/facility/stock/history", facilityHandler.GetStockHistory).Methods("GET")
 
-	// Message tanpa middleware
-	router.HandleFunc("/api/message", websockerHandler.HandleConnection)
+	// Callback status SMS / WhatsApp dari provider (diautentikasi dengan shared secret)
+	router.HandleFunc("/api/webhooks/messaging/status", messagingHandler.DeliveryStatus).Methods("POST")
+
+	// Websocket chat, token dikirim lewat header Authorization atau tiket dari /api/ws/ticket: ?ticket=<tiket>
+	router.Handle("/api/message", authMiddleware.AuthenticateWebSocket(http.HandlerFunc(websockerHandler.HandleConnection)))
 
 	// Protected routes
 	protected := router.PathPrefix("/api").Subrouter()
@@ -71,10 +85,72 @@ func SetupRoutes(
 		w.Write([]byte("Protected route"))
 	}).Methods("GET")
 	protected.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
+	protected.HandleFunc("/ws/ticket", authHandler.WebSocketTicket).Methods("POST")
 
 	// profile routes
 	protected.HandleFunc("/user/profile", profileHandler.GetProfile).Methods("GET")
 	protected.HandleFunc("/user/profile/photo", profileHandler.UpdateProfilePhoto).Methods("POST")
+	protected.HandleFunc("/user/profile/location", profileHandler.UpdateLocation).Methods("PUT")
+	protected.HandleFunc("/user/profile/blood", profileHandler.UpdateBloodType).Methods("PUT")
+
+	// blood request routes
+	protected.HandleFunc("/blood-request", bloodRequestHandler.Create).Methods("POST")
+	protected.HandleFunc("/blood-request", bloodRequestHandler.GetByID).Methods("GET")
+	protected.HandleFunc("/blood-request", bloodRequestHandler.Update).Methods("PUT")
+	protected.HandleFunc("/blood-request/cancel", bloodRequestHandler.Cancel).Methods("POST")
+	protected.HandleFunc("/blood-request/fulfill", bloodRequestHandler.Fulfill).Methods("POST")
+	protected.HandleFunc("/blood-request/donors", bloodRequestHandler.GetNearbyDonors).Methods("GET")
+	protected.HandleFunc("/blood-request/escalations", bloodRequestHandler.GetEscalations).Methods("GET")
+	protected.HandleFunc("/blood-requests", bloodRequestHandler.GetMine).Methods("GET")
+	protected.HandleFunc("/blood-requests/feed", bloodRequestHandler.GetFeed).Methods("GET")
+
+	// pledge routes
+	protected.HandleFunc("/pledge", pledgeHandler.Pledge).Methods("POST")
+	protected.HandleFunc("/pledge/accept", pledgeHandler.Accept).Methods("POST")
+	protected.HandleFunc("/pledge/decline", pledgeHandler.Decline).Methods("POST")
+	protected.HandleFunc("/pledge/no-show", pledgeHandler.NoShow).Methods("POST")
+	protected.HandleFunc("/pledges", pledgeHandler.GetByBloodRequest).Methods("GET")
+	protected.HandleFunc("/pledges/mine", pledgeHandler.GetMine).Methods("GET")
+
+	// History donor dicatat atas nama user yang login
+	protected.HandleFunc("/history", historyHandler.PostHistory).Methods("POST")
+
+	// facility routes (admin dan petugas fasilitas)
+	protected.HandleFunc("/facility", facilityHandler.Create).Methods("POST")
+	protected.HandleFunc("/facility", facilityHandler.Update).Methods("PUT")
+	protected.HandleFunc("/facility", facilityHandler.Delete).Methods("DELETE")
+	protected.HandleFunc("/facility/partner", facilityHandler.AddPartner).Methods("POST")
+	protected.HandleFunc("/facility/stock", facilityHandler.ReportStock).Methods("PUT")
+
+	// notification inbox routes
+	protected.HandleFunc("/notifications", notificationHandler.List).Methods("GET")
+	protected.HandleFunc("/notifications/unread-count", notificationHandler.UnreadCount).Methods("GET")
+	protected.HandleFunc("/notifications/read", notificationHandler.MarkAllRead).Methods("PUT")
+	protected.HandleFunc("/notification/read", notificationHandler.MarkRead).Methods("PUT")
+	protected.HandleFunc("/notification-preferences", notificationHandler.GetPreferences).Methods("GET")
+	protected.HandleFunc("/notification-preferences", notificationHandler.UpdatePreferences).Methods("PUT")
+
+	// device token routes (satu user bisa punya banyak perangkat)
+	protected.HandleFunc("/device-tokens", deviceTokenHandler.GetMine).Methods("GET")
+	protected.HandleFunc("/device-token", deviceTokenHandler.Register).Methods("POST")
+	protected.HandleFunc("/device-token", deviceTokenHandler.Unregister).Methods("DELETE")
+
+	// daftar percakapan chat
+	protected.HandleFunc("/conversations", conversationHandler.List).Methods("GET")
+
+	// SMS / WhatsApp untuk request urgent
+	protected.HandleFunc("/messaging/preference", messagingHandler.GetPreference).Methods("GET")
+	protected.HandleFunc("/messaging/opt-in", messagingHandler.OptIn).Methods("POST")
+	protected.HandleFunc("/messaging/opt-out", messagingHandler.OptOut).Methods("POST")
+
+	// broadcast blood request oleh pencari, kill switch untuk admin
+	protected.HandleFunc("/broadcast", fcmHandler.SendFCM).Methods("POST")
+	protected.HandleFunc("/admin/broadcast/kill-switch", fcmHandler.GetKillSwitch).Methods("GET")
+	protected.HandleFunc("/admin/broadcast/kill-switch", fcmHandler.SetKillSwitch).Methods("PUT")
+
+	// outbox notifikasi (admin)
+	protected.HandleFunc("/admin/outbox", outboxHandler.GetMessages).Methods("GET")
+	protected.HandleFunc("/admin/outbox/replay", outboxHandler.Replay).Methods("POST")
 
 	// education routes
 	// protected.HandleFunc("/educations",eduHandler.GetEducations).Methods("GET")
@@ -100,9 +176,6 @@ func SetupRoutes(
 	// protected.HandleFunc("/reward", rewardHandler.ClaimReward).Methods("POST")
 	// protected.HandleFunc("/reward/balance", rewardHandler.GetBalance).Methods("GET")
 
-	// fcm routes
-	// protected.HandleFunc("/broadcast", fcmHandler.SendFCM).Methods("POST")
-
 	// message routes
 	// protected.HandleFunc("/message", websockerHandler.HandleConnection).Methods("GET")
 }
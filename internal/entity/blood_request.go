@@ -0,0 +1,89 @@
+package entity
+
+import "time"
+
+const (
+	UrgencyNormal   = 1
+	UrgencyUrgent   = 2
+	UrgencyCritical = 3
+)
+
+type BloodRequestStatus string
+
+const (
+	BloodRequestOpen             BloodRequestStatus = "open"
+	BloodRequestPartiallyPledged BloodRequestStatus = "partially_pledged"
+	BloodRequestFulfilled        BloodRequestStatus = "fulfilled"
+	BloodRequestCancelled        BloodRequestStatus = "cancelled"
+	BloodRequestExpired          BloodRequestStatus = "expired"
+)
+
+// IsActive menandakan request masih menerima pendonor dan boleh di-broadcast
+func (s BloodRequestStatus) IsActive() bool {
+	return s == BloodRequestOpen || s == BloodRequestPartiallyPledged
+}
+
+type BloodRequest struct {
+	ID                 uint               `json:"id"`
+	UserID             uint               `json:"user_id"`
+	SearchName         string             `json:"search_name"`
+	Location           string             `json:"location"`
+	FacilityID         *uint              `json:"facility_id"`
+	Latitude           *float64           `json:"latitude"`
+	Longitude          *float64           `json:"longitude"`
+	BloodType          string             `json:"blood_type"`
+	Rhesus             string             `json:"rhesus"`
+	Total              int                `json:"total"`
+	Available          bool               `json:"available"`
+	Urgency            int                `json:"urgency"`
+	Status             BloodRequestStatus `json:"status"`
+	PledgedCount       int                `json:"pledged_count"`
+	Deadline           *time.Time         `json:"deadline"`
+	EscalationLevel    int                `json:"escalation_level"`
+	EscalatedAt        *time.Time         `json:"escalated_at,omitempty"`
+	PartiallyPledgedAt *time.Time         `json:"partially_pledged_at,omitempty"`
+	FulfilledAt        *time.Time         `json:"fulfilled_at,omitempty"`
+	CancelledAt        *time.Time         `json:"cancelled_at,omitempty"`
+	ExpiredAt          *time.Time         `json:"expired_at,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
+}
+
+type EscalationAction string
+
+const (
+	EscalationWiderRadius         EscalationAction = "wider_radius"
+	EscalationAllCompatibleGroups EscalationAction = "all_compatible_groups"
+	EscalationAdmins              EscalationAction = "admins"
+)
+
+// BloodRequestEscalation mencatat setiap langkah eskalasi sebuah blood request
+type BloodRequestEscalation struct {
+	ID             uint             `json:"id"`
+	BloodRequestID uint             `json:"blood_request_id"`
+	Level          int              `json:"level"`
+	Action         EscalationAction `json:"action"`
+	Notified       int              `json:"notified"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// BloodRequestFeedItem adalah blood request di feed pendonor beserta jaraknya dari pendonor
+type BloodRequestFeedItem struct {
+	BloodRequest
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+	// SortDistance dipakai untuk cursor; 0 jika pendonor belum mengisi lokasi
+	SortDistance float64 `json:"-"`
+}
+
+// FeedCursor adalah posisi terakhir feed: urutan urgency DESC, jarak ASC, id ASC
+type FeedCursor struct {
+	Urgency    int
+	DistanceKm float64
+	ID         uint
+}
+
+type BloodRequestFeed struct {
+	Items        []*BloodRequestFeedItem `json:"items"`
+	NextCursor   string                  `json:"next_cursor,omitempty"`
+	NextDonation *time.Time              `json:"next_donation,omitempty"`
+}
@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+type DevicePlatform string
+
+const (
+	DeviceAndroid DevicePlatform = "android"
+	DeviceIOS     DevicePlatform = "ios"
+	DeviceWeb     DevicePlatform = "web"
+	// DeviceUnknown dipakai untuk token yang dikirim lewat register/login tanpa info perangkat
+	DeviceUnknown DevicePlatform = "unknown"
+)
+
+func (p DevicePlatform) IsValid() bool {
+	switch p {
+	case DeviceAndroid, DeviceIOS, DeviceWeb, DeviceUnknown:
+		return true
+	}
+	return false
+}
+
+// DeviceToken adalah token FCM satu perangkat milik user; satu user bisa punya banyak perangkat
+type DeviceToken struct {
+	ID         uint           `json:"id"`
+	UserID     uint           `json:"user_id"`
+	Token      string         `json:"token"`
+	Platform   DevicePlatform `json:"platform"`
+	AppVersion string         `json:"app_version,omitempty"`
+	LastSeenAt time.Time      `json:"last_seen_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	// LegacyTopicsCleared menandai perangkat yang sudah di-unsubscribe dari topic lama blood_<type>
+	LegacyTopicsCleared bool `json:"-"`
+}
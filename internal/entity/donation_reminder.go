@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// DonationReminder mencatat pengingat "sudah bisa donor lagi" yang dikirim untuk satu masa
+// tunggu, sehingga pendonor tidak diingatkan dua kali untuk tanggal yang sama. Pengingat yang
+// belum sampai lewat channel mana pun dicoba lagi pada NextAttemptAt.
+type DonationReminder struct {
+	ID            uint       `json:"id"`
+	UserID        uint       `json:"user_id"`
+	EligibleOn    time.Time  `json:"eligible_on"`
+	PushSent      bool       `json:"push_sent"`
+	EmailSent     bool       `json:"email_sent"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,72 @@
+package entity
+
+import "time"
+
+type FacilityType string
+
+const (
+	FacilityPMIUDD     FacilityType = "pmi_udd"
+	FacilityHospital   FacilityType = "hospital"
+	FacilityMobileUnit FacilityType = "mobile_unit"
+)
+
+func (t FacilityType) IsValid() bool {
+	return t == FacilityPMIUDD || t == FacilityHospital || t == FacilityMobileUnit
+}
+
+// Facility adalah tempat donor darah: PMI UDD, rumah sakit atau mobil unit
+type Facility struct {
+	ID           uint         `json:"id"`
+	Name         string       `json:"name"`
+	Type         FacilityType `json:"type"`
+	Address      string       `json:"address"`
+	Latitude     float64      `json:"latitude"`
+	Longitude    float64      `json:"longitude"`
+	OpeningHours string       `json:"opening_hours"`
+	Contact      string       `json:"contact"`
+	BloodTypes   []string     `json:"blood_types"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type NearbyFacility struct {
+	Facility
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// StockLevel adalah jumlah kantong darah satu golongan yang dilaporkan fasilitas
+type StockLevel struct {
+	BloodType string `json:"blood_type"`
+	Rhesus    string `json:"rhesus"`
+	Units     int    `json:"units"`
+}
+
+type FacilityStock struct {
+	FacilityID uint      `json:"facility_id"`
+	BloodType  string    `json:"blood_type"`
+	Rhesus     string    `json:"rhesus"`
+	Units      int       `json:"units"`
+	Shortage   bool      `json:"shortage"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type FacilityStockHistory struct {
+	ID         uint      `json:"id"`
+	FacilityID uint      `json:"facility_id"`
+	BloodType  string    `json:"blood_type"`
+	Rhesus     string    `json:"rhesus"`
+	Units      int       `json:"units"`
+	ReportedBy *uint     `json:"reported_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type FacilityShortage struct {
+	ID         uint       `json:"id"`
+	FacilityID uint       `json:"facility_id"`
+	BloodType  string     `json:"blood_type"`
+	Rhesus     string     `json:"rhesus"`
+	Units      int        `json:"units"`
+	Notified   int        `json:"notified"`
+	RaisedAt   time.Time  `json:"raised_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
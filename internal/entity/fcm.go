@@ -1,13 +1,30 @@
 package entity
 
-type Fcm struct {
-	AccessToken string `json:"access_token"`
-	ProjectID   string `json:"project_id"`
-}
+import "time"
 
+// RequestFcm adalah permintaan broadcast dari pencari. Pengirim diambil dari JWT dan teks
+// notifikasi dibuat server dari data blood request.
 type RequestFcm struct {
-	UserID    uint   `json:"user_id"`
-	BloodType string `json:"blood_type"`
-	Title     string `json:"title"`
-	Body      string `json:"body"`
+	BloodRequestID uint `json:"blood_request_id"`
+}
+
+// BloodBroadcast mencatat setiap broadcast pencari untuk rate limit dan deteksi duplikat
+type BloodBroadcast struct {
+	ID             uint      `json:"id"`
+	UserID         uint      `json:"user_id"`
+	BloodRequestID uint      `json:"blood_request_id"`
+	Title          string    `json:"title"`
+	Body           string    `json:"body"`
+	ContentHash    string    `json:"-"`
+	OutboxID       uint      `json:"outbox_id"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TopicSubscription mencatat topic FCM yang sedang diikuti token milik user
+type TopicSubscription struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	FCMToken  string    `json:"-"`
+	Topic     string    `json:"topic"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// FlagBroadcastsDisabled adalah kill switch admin untuk semua broadcast pencari
+const FlagBroadcastsDisabled = "broadcasts_disabled"
+
+// FeatureFlag adalah saklar operasional yang bisa diubah admin tanpa deploy ulang
+type FeatureFlag struct {
+	Name      string    `json:"name"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedBy *uint     `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -6,7 +6,9 @@ type History struct {
 	ID             uint      `json:"id"`
 	UserID         uint      `json:"user_id"`
 	BloodRequestID uint      `json:"blood_request_id"`
+	FacilityID     *uint     `json:"facility_id"`
 	ImageDonor     string    `json:"image_donor"`
+	Verified       bool      `json:"verified"`
 	NextDonation   time.Time `json:"next_donation"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
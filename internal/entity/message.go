@@ -3,20 +3,78 @@ package entity
 import "time"
 
 type Message struct {
-	ID          uint      `json:"id"`
-	SenderID    uint      `json:"sender_id"`
-	ReceiverID  uint      `json:"receiver_id"`
-	Content     string    `json:"content"`
-	IsDelivered bool      `json:"is_delivered"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          uint       `json:"id"`
+	SenderID    uint       `json:"sender_id"`
+	ReceiverID  uint       `json:"receiver_id"`
+	Content     string     `json:"content"`
+	ClientMsgID string     `json:"client_msg_id,omitempty"`
+	IsDelivered bool       `json:"is_delivered"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// MessageRequest adalah pesan chat lewat websocket. ClientMsgID dibuat client dan dipakai
+// untuk deduplikasi saat client mengirim ulang; ID diisi server setelah pesan disimpan.
 type MessageRequest struct {
-	SenderID   uint      `json:"sender_id"`
-	ReceiverID uint      `json:"receiver_id"`
-	Content    string    `json:"content"`
-	CreatedAt  time.Time `json:"created_at"`
+	Type        ChatEventType `json:"type,omitempty"`
+	ID          uint          `json:"id,omitempty"`
+	ClientMsgID string        `json:"client_msg_id,omitempty"`
+	SenderID    uint          `json:"sender_id"`
+	ReceiverID  uint          `json:"receiver_id"`
+	Content     string        `json:"content"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// ChatEventType adalah jenis frame di websocket chat
+type ChatEventType string
+
+const (
+	ChatEventMessage     ChatEventType = "message"
+	ChatEventRead        ChatEventType = "read"
+	ChatEventTypingStart ChatEventType = "typing_start"
+	ChatEventTypingStop  ChatEventType = "typing_stop"
+	// dikirim server ketika daftar percakapan user berubah
+	ChatEventConversationUpdate ChatEventType = "conversation_update"
+)
+
+// ChatEvent adalah envelope frame dari client. ReceiverID selalu lawan bicara: penerima pesan,
+// pengirim pesan yang dibaca (read), atau user yang melihat indikator mengetik.
+// Frame tanpa type dianggap pesan agar client lama tetap berfungsi.
+type ChatEvent struct {
+	Type        ChatEventType `json:"type"`
+	ClientMsgID string        `json:"client_msg_id,omitempty"`
+	SenderID    uint          `json:"sender_id"`
+	ReceiverID  uint          `json:"receiver_id"`
+	Content     string        `json:"content,omitempty"`
+	// MessageID pada event read: semua pesan dari ReceiverID sampai ID ini sudah dibaca
+	MessageID uint       `json:"message_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+// Conversation adalah satu lawan bicara di daftar chat beserta pesan terakhirnya
+type Conversation struct {
+	PeerID        uint      `json:"peer_id"`
+	PeerName      string    `json:"peer_name"`
+	PeerPhoto     *string   `json:"peer_photo"`
+	LastMessageID uint      `json:"last_message_id"`
+	LastSenderID  uint      `json:"last_sender_id"`
+	LastMessage   string    `json:"last_message"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	UnreadCount   int       `json:"unread_count"`
+}
+
+// ConversationPage diurutkan dari percakapan terbaru; NextCursor adalah last_message_id
+// percakapan terakhir di halaman
+type ConversationPage struct {
+	Items      []*Conversation `json:"items"`
+	NextCursor uint            `json:"next_cursor,omitempty"`
+}
+
+// ConversationUpdate dikirim lewat websocket ketika satu percakapan berubah
+type ConversationUpdate struct {
+	Type         ChatEventType `json:"type"`
+	Conversation *Conversation `json:"conversation"`
 }
 
 type ChatHistoryRequest struct {
@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+type NotificationType string
+
+const (
+	NotificationBloodRequest     NotificationType = "blood_request"
+	NotificationDonation         NotificationType = "donation"
+	NotificationStockShortage    NotificationType = "stock_shortage"
+	NotificationAdminAlert       NotificationType = "admin_alert"
+	NotificationGeneral          NotificationType = "general"
+	NotificationChatMessage      NotificationType = "chat_message"
+	NotificationDonationReminder NotificationType = "donation_reminder"
+)
+
+// Notification adalah salinan push notification yang disimpan di inbox user
+type Notification struct {
+	ID        uint              `json:"id"`
+	UserID    uint              `json:"user_id"`
+	Type      NotificationType  `json:"type"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Payload   map[string]string `json:"payload"`
+	Target    string            `json:"target,omitempty"`
+	ReadAt    *time.Time        `json:"read_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type NotificationPage struct {
+	Items      []*Notification `json:"items"`
+	NextCursor uint            `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,129 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotificationCategory adalah kelompok notifikasi yang bisa diatur user
+type NotificationCategory string
+
+const (
+	CategoryUrgentRequest   NotificationCategory = "urgent_request"
+	CategoryMatchingRequest NotificationCategory = "matching_request"
+	CategoryChat            NotificationCategory = "chat"
+	CategoryReminder        NotificationCategory = "reminder"
+	CategoryReward          NotificationCategory = "reward"
+	CategoryEducation       NotificationCategory = "education"
+)
+
+// DefaultTimezone dipakai untuk user yang belum mengatur zona waktu
+const DefaultTimezone = "Asia/Jakarta"
+
+// NotificationPreference adalah pengaturan push notification milik user.
+// QuietHoursStart/End berformat "HH:MM" di zona waktu user; kosong berarti tanpa quiet hours.
+// MaxPerDay 0 berarti tanpa batas harian. Keduanya kosong secara default sehingga push hanya
+// ditahan setelah user mengaturnya sendiri.
+type NotificationPreference struct {
+	UserID           uint      `json:"user_id"`
+	UrgentRequests   bool      `json:"urgent_requests"`
+	MatchingRequests bool      `json:"matching_requests"`
+	Chat             bool      `json:"chat"`
+	Reminders        bool      `json:"reminders"`
+	Rewards          bool      `json:"rewards"`
+	Education        bool      `json:"education"`
+	MaxPerDay        int       `json:"max_per_day"`
+	QuietHoursStart  string    `json:"quiet_hours_start"`
+	QuietHoursEnd    string    `json:"quiet_hours_end"`
+	Timezone         string    `json:"timezone"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// DefaultNotificationPreference dipakai selama user belum menyimpan preferensi sendiri
+func DefaultNotificationPreference(userID uint) *NotificationPreference {
+	return &NotificationPreference{
+		UserID:           userID,
+		UrgentRequests:   true,
+		MatchingRequests: true,
+		Chat:             true,
+		Reminders:        true,
+		Rewards:          true,
+		Education:        true,
+		Timezone:         DefaultTimezone,
+	}
+}
+
+// Allows mengembalikan apakah user mau menerima kategori notifikasi ini
+func (p *NotificationPreference) Allows(category NotificationCategory) bool {
+	switch category {
+	case CategoryUrgentRequest:
+		return p.UrgentRequests
+	case CategoryMatchingRequest:
+		return p.MatchingRequests
+	case CategoryChat:
+		return p.Chat
+	case CategoryReminder:
+		return p.Reminders
+	case CategoryReward:
+		return p.Rewards
+	case CategoryEducation:
+		return p.Education
+	}
+	return true
+}
+
+// Location mengembalikan zona waktu user, atau DefaultTimezone jika tidak valid
+func (p *NotificationPreference) Location() *time.Location {
+	if loc, err := time.LoadLocation(p.Timezone); err == nil {
+		return loc
+	}
+
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InQuietHours mengecek apakah now jatuh di quiet hours user. Rentang yang melewati tengah
+// malam (mis. 22:00-06:00) didukung.
+func (p *NotificationPreference) InQuietHours(now time.Time) bool {
+	start, errStart := ParseClock(p.QuietHoursStart)
+	end, errEnd := ParseClock(p.QuietHoursEnd)
+	if errStart != nil || errEnd != nil || start == end {
+		return false
+	}
+
+	local := now.In(p.Location())
+	minute := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// QuietHoursUntil mengembalikan waktu quiet hours yang sedang berjalan berakhir, atau now jika
+// now tidak jatuh di quiet hours
+func (p *NotificationPreference) QuietHoursUntil(now time.Time) time.Time {
+	end, err := ParseClock(p.QuietHoursEnd)
+	if err != nil || !p.InQuietHours(now) {
+		return now
+	}
+
+	local := now.In(p.Location())
+	at := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, local.Location())
+	if !at.After(local) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
+
+// ParseClock mengubah "HH:MM" menjadi menit sejak tengah malam
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestNotificationPreferenceInQuietHours(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		timezone   string
+		now        time.Time
+		want       bool
+	}{
+		{"no quiet hours", "", "", "UTC", time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), false},
+		{"same start and end", "22:00", "22:00", "UTC", time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC), false},
+		{"invalid clock", "25:00", "06:00", "UTC", time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC), false},
+		{"daytime range inside", "09:00", "17:00", "UTC", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), true},
+		{"daytime range at start", "09:00", "17:00", "UTC", time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), true},
+		{"daytime range at end", "09:00", "17:00", "UTC", time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC), false},
+		{"daytime range outside", "09:00", "17:00", "UTC", time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), false},
+		{"overnight before midnight", "22:00", "06:00", "UTC", time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), true},
+		{"overnight after midnight", "22:00", "06:00", "UTC", time.Date(2024, 1, 1, 5, 59, 0, 0, time.UTC), true},
+		{"overnight at end", "22:00", "06:00", "UTC", time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC), false},
+		{"overnight outside", "22:00", "06:00", "UTC", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), false},
+		// 15:30 UTC adalah 22:30 WIB
+		{"user timezone", "22:00", "06:00", "Asia/Jakarta", time.Date(2024, 1, 1, 15, 30, 0, 0, time.UTC), true},
+		{"user timezone outside", "22:00", "06:00", "Asia/Jakarta", time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &NotificationPreference{QuietHoursStart: tt.start, QuietHoursEnd: tt.end, Timezone: tt.timezone}
+			if got := p.InQuietHours(tt.now); got != tt.want {
+				t.Errorf("InQuietHours(%s) = %v, want %v", tt.now.Format(time.RFC3339), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultNotificationPreferenceDoesNotSuppress(t *testing.T) {
+	p := DefaultNotificationPreference(1)
+	if p.MaxPerDay != 0 {
+		t.Errorf("MaxPerDay = %d, want 0", p.MaxPerDay)
+	}
+
+	for hour := 0; hour < 24; hour++ {
+		now := time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC)
+		if p.InQuietHours(now) {
+			t.Errorf("InQuietHours(%02d:00) = true, want false", hour)
+		}
+	}
+}
+
+func TestNotificationPreferenceQuietHoursUntil(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		timezone   string
+		now        time.Time
+		want       time.Time
+	}{
+		{"outside quiet hours", "22:00", "06:00", "UTC", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"daytime range", "09:00", "17:00", "UTC", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC)},
+		{"overnight before midnight", "22:00", "06:00", "UTC", time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC), time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC)},
+		{"overnight after midnight", "22:00", "06:00", "UTC", time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC)},
+		// 15:30 UTC adalah 22:30 WIB, quiet hours berakhir 06:00 WIB = 23:00 UTC
+		{"user timezone", "22:00", "06:00", "Asia/Jakarta", time.Date(2024, 1, 1, 15, 30, 0, 0, time.UTC), time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &NotificationPreference{QuietHoursStart: tt.start, QuietHoursEnd: tt.end, Timezone: tt.timezone}
+			if got := p.QuietHoursUntil(tt.now); !got.Equal(tt.want) {
+				t.Errorf("QuietHoursUntil(%s) = %s, want %s", tt.now.Format(time.RFC3339), got.Format(time.RFC3339), tt.want.Format(time.RFC3339))
+			}
+		})
+	}
+}
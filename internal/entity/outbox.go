@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"backend/pkg/bloodcompat"
+	"encoding/json"
+	"time"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	// OutboxDead berarti percobaan sudah habis, hanya bisa dikirim ulang lewat replay admin
+	OutboxDead OutboxStatus = "dead"
+)
+
+func (s OutboxStatus) IsValid() bool {
+	switch s {
+	case OutboxPending, OutboxSent, OutboxDead:
+		return true
+	}
+	return false
+}
+
+type OutboxKind string
+
+const (
+	OutboxPushUser    OutboxKind = "push_user"
+	OutboxBroadcast   OutboxKind = "blood_request_broadcast"
+	OutboxDonorsNear  OutboxKind = "donors_near"
+	OutboxTextMessage OutboxKind = "text_message"
+	OutboxEscalation  OutboxKind = "blood_request_escalation"
+)
+
+// OutboxMessage adalah notifikasi yang ditulis bersama perubahan data lalu dikirim oleh worker
+type OutboxMessage struct {
+	ID            uint            `json:"id"`
+	Kind          OutboxKind      `json:"kind"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        OutboxStatus    `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty"`
+}
+
+// PushUserPayload dikirim ke satu user lewat FCMUseCase.SendToUser
+type PushUserPayload struct {
+	UserID uint              `json:"user_id"`
+	Title  string            `json:"title"`
+	Body   string            `json:"body"`
+	Data   map[string]string `json:"data"`
+}
+
+// BroadcastPayload dikirim lewat FCMUseCase.SendFCMV1
+type BroadcastPayload struct {
+	UserID         uint   `json:"user_id"`
+	BloodRequestID uint   `json:"blood_request_id"`
+	BloodType      string `json:"blood_type"`
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+}
+
+// DonorsNearPayload dikirim lewat FCMUseCase.NotifyDonorsNear. ShortageID diisi jika
+// notifikasi berasal dari shortage stok agar jumlah pendonor yang dihubungi tercatat.
+type DonorsNearPayload struct {
+	ShortageID uint                `json:"shortage_id,omitempty"`
+	Latitude   float64             `json:"latitude"`
+	Longitude  float64             `json:"longitude"`
+	RadiusKm   float64             `json:"radius_km"`
+	Groups     []bloodcompat.Group `json:"groups"`
+	Title      string              `json:"title"`
+	Body       string              `json:"body"`
+	Data       map[string]string   `json:"data"`
+}
+
+// TextMessagePayload dikirim lewat MessagingUseCase.DeliverText. Isi pesan sudah tersimpan
+// di text_messages sehingga payload hanya berisi id-nya.
+type TextMessagePayload struct {
+	TextMessageID uint `json:"text_message_id"`
+}
+
+// EscalationPayload dikirim lewat EscalationUseCase.DeliverEscalation. Level dan aksi sudah
+// tercatat di blood_request_escalations sehingga payload hanya berisi id-nya.
+type EscalationPayload struct {
+	EscalationID uint `json:"escalation_id"`
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+type PledgeStatus string
+
+const (
+	PledgePending  PledgeStatus = "pending"
+	PledgeAccepted PledgeStatus = "accepted"
+	PledgeDeclined PledgeStatus = "declined"
+	PledgeNoShow   PledgeStatus = "no_show"
+	// PledgeDonated menandai pledge yang sudah dipakai untuk mencatat donasi
+	PledgeDonated PledgeStatus = "donated"
+)
+
+type Pledge struct {
+	ID             uint         `json:"id"`
+	BloodRequestID uint         `json:"blood_request_id"`
+	DonorID        uint         `json:"donor_id"`
+	Status         PledgeStatus `json:"status"`
+	AcceptedAt     *time.Time   `json:"accepted_at,omitempty"`
+	DeclinedAt     *time.Time   `json:"declined_at,omitempty"`
+	NoShowAt       *time.Time   `json:"no_show_at,omitempty"`
+	DonatedAt      *time.Time   `json:"donated_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
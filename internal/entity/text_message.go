@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+type TextMessageStatus string
+
+const (
+	TextMessageQueued    TextMessageStatus = "queued"
+	TextMessageSent      TextMessageStatus = "sent"
+	TextMessageDelivered TextMessageStatus = "delivered"
+	TextMessageFailed    TextMessageStatus = "failed"
+)
+
+func (s TextMessageStatus) IsValid() bool {
+	switch s {
+	case TextMessageQueued, TextMessageSent, TextMessageDelivered, TextMessageFailed:
+		return true
+	}
+	return false
+}
+
+// MessagingPreference mencatat persetujuan user menerima SMS / WhatsApp untuk request
+// darah urgent yang tidak bisa dikirim lewat push
+type MessagingPreference struct {
+	UserID     uint       `json:"user_id"`
+	OptedIn    bool       `json:"opted_in"`
+	Channel    string     `json:"channel"`
+	OptedInAt  *time.Time `json:"opted_in_at,omitempty"`
+	OptedOutAt *time.Time `json:"opted_out_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// MessagingRecipient adalah user yang sudah opt-in beserta nomor teleponnya
+type MessagingRecipient struct {
+	UserID      uint
+	Channel     string
+	PhoneNumber string
+}
+
+// TextMessage adalah satu SMS / WhatsApp yang dikirim beserta status pengirimannya
+type TextMessage struct {
+	ID                uint              `json:"id"`
+	UserID            uint              `json:"user_id"`
+	BloodRequestID    *uint             `json:"blood_request_id,omitempty"`
+	Channel           string            `json:"channel"`
+	ToNumber          string            `json:"to_number"`
+	Body              string            `json:"body"`
+	Status            TextMessageStatus `json:"status"`
+	ProviderMessageID string            `json:"provider_message_id,omitempty"`
+	Error             string            `json:"error,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
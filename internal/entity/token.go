@@ -8,6 +8,8 @@ const (
 	ResetPassword TokenType = "reset_password"
 	EmailVerify   TokenType = "email_verify"
 	Blacklisted   TokenType = "black_list"
+	// WebSocketTicket adalah tiket sekali pakai untuk membuka koneksi websocket chat
+	WebSocketTicket TokenType = "ws_ticket"
 )
 
 type Token struct {
@@ -9,7 +9,7 @@ type User struct {
 	Role          string    `json:"role"`
 	Name          string    `json:"name"`
 	DateOfBirth   time.Time `json:"date_of_birth"`
-	ProfilePhoto  *string    `json:"profile_photo"`
+	ProfilePhoto  *string   `json:"profile_photo"`
 	PhoneNumber   string    `json:"phone_number"`
 	Gender        string    `json:"gender"`
 	Address       string    `json:"address"`
@@ -18,7 +18,8 @@ type User struct {
 	GoogleID      *string   `json:"-"`
 	TotalDonation int       `json:"total_donation"`
 	Coin          int       `json:"coin"`
-	FCMToken      string    `json:"fcm_token"`
+	Latitude      *float64  `json:"latitude"`
+	Longitude     *float64  `json:"longitude"`
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 }
@@ -30,3 +31,12 @@ func NewGoogleUser(email, name, googleID string) *User {
 		GoogleID: &googleID,
 	}
 }
+
+// NearbyDonor adalah pendonor hasil pencarian berdasarkan jarak
+type NearbyDonor struct {
+	UserID     uint    `json:"user_id"`
+	Name       string  `json:"name"`
+	BloodType  string  `json:"blood_type"`
+	Rhesus     string  `json:"rhesus"`
+	DistanceKm float64 `json:"distance_km"`
+}
@@ -49,6 +49,18 @@ BEGIN
   IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'gender') THEN
   	CREATE TYPE gender AS ENUM ('male', 'female');
   END IF;
+
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'blood_request_status') THEN
+    CREATE TYPE blood_request_status AS ENUM ('open', 'partially_pledged', 'fulfilled', 'cancelled', 'expired');
+  END IF;
+
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'facility_type') THEN
+    CREATE TYPE facility_type AS ENUM ('pmi_udd', 'hospital', 'mobile_unit');
+  END IF;
+
+  IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'outbox_status') THEN
+    CREATE TYPE outbox_status AS ENUM ('pending', 'sent', 'dead');
+  END IF;
 END$$;
 
 CREATE TABLE IF NOT EXISTS users (
@@ -67,6 +79,7 @@ CREATE TABLE IF NOT EXISTS users (
     google_id VARCHAR(255),
 	total_donation INT DEFAULT 0,
 	coin INT DEFAULT 0,
+	-- kolom lama, tidak dipakai lagi; isinya dipindahkan sekali ke device_tokens lalu dikosongkan
 	fcm_token VARCHAR(255),
     created_at TIMESTAMP NOT NULL,
     updated_at TIMESTAMP NOT NULL
@@ -74,6 +87,115 @@ CREATE TABLE IF NOT EXISTS users (
 
 CREATE UNIQUE INDEX IF NOT EXISTS users_google_id_key ON users (google_id) WHERE google_id IS NOT NULL;
 
+ALTER TABLE users ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+CREATE INDEX IF NOT EXISTS idx_users_role_location ON users(role, latitude, longitude);
+
+CREATE TABLE IF NOT EXISTS device_tokens (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	token VARCHAR(255) NOT NULL UNIQUE,
+	platform VARCHAR(20) NOT NULL DEFAULT 'unknown',
+	app_version VARCHAR(50),
+	last_seen_at TIMESTAMP NOT NULL,
+	legacy_topics_cleared_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_tokens_user ON device_tokens (user_id);
+
+-- token lama di users.fcm_token dipindahkan sekali sebagai perangkat pertama lalu dikosongkan,
+-- sehingga token yang sudah dihapus dari device_tokens tidak kembali saat restart
+WITH legacy AS (
+	UPDATE users u SET fcm_token = NULL
+	FROM users old
+	WHERE old.id = u.id AND old.fcm_token IS NOT NULL AND old.fcm_token <> ''
+	RETURNING u.id, old.fcm_token, u.updated_at
+)
+INSERT INTO device_tokens (user_id, token, platform, last_seen_at)
+SELECT id, fcm_token, 'unknown', updated_at FROM legacy
+ON CONFLICT (token) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS topic_subscriptions (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	fcm_token VARCHAR(255) NOT NULL,
+	topic VARCHAR(100) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- langganan topic dicatat per perangkat, bukan per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_topic_subscriptions_token_topic ON topic_subscriptions (fcm_token, topic);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(50) NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	body TEXT NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	target VARCHAR(255),
+	read_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	urgent_requests BOOLEAN NOT NULL DEFAULT TRUE,
+	matching_requests BOOLEAN NOT NULL DEFAULT TRUE,
+	chat BOOLEAN NOT NULL DEFAULT TRUE,
+	reminders BOOLEAN NOT NULL DEFAULT TRUE,
+	rewards BOOLEAN NOT NULL DEFAULT TRUE,
+	education BOOLEAN NOT NULL DEFAULT TRUE,
+	max_per_day INT NOT NULL DEFAULT 0,
+	quiet_hours_start VARCHAR(5),
+	quiet_hours_end VARCHAR(5),
+	timezone VARCHAR(64) NOT NULL DEFAULT 'Asia/Jakarta',
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- jumlah push per user per hari (tanggal di zona waktu user) untuk batas harian
+CREATE TABLE IF NOT EXISTS notification_push_counts (
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	day DATE NOT NULL,
+	count INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+	name VARCHAR(100) PRIMARY KEY,
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_by INT REFERENCES users(id) ON DELETE SET NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS notification_outbox (
+	id SERIAL PRIMARY KEY,
+	kind VARCHAR(50) NOT NULL,
+	payload JSONB NOT NULL,
+	status outbox_status NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox (next_attempt_at) WHERE status = 'pending';
+
+-- penerima yang sudah dijangkau oleh message multi-penerima, agar retry hanya mengirim ke sisanya
+CREATE TABLE IF NOT EXISTS notification_outbox_recipients (
+	outbox_id INT NOT NULL REFERENCES notification_outbox(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	reached_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (outbox_id, user_id)
+);
+
 CREATE TABLE IF NOT EXISTS tokens (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -112,6 +234,74 @@ CREATE INDEX IF NOT EXISTS idx_messages_sender_id ON messages(sender_id);
 CREATE INDEX IF NOT EXISTS idx_messages_receiver_id ON messages(receiver_id);
 CREATE INDEX IF NOT EXISTS idx_messages_delivery_status ON messages(receiver_id, is_delivered);
 
+-- client_msg_id dibuat client agar pesan yang dikirim ulang tidak tersimpan dua kali
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS client_msg_id VARCHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_client_msg_id ON messages(sender_id, client_msg_id) WHERE client_msg_id IS NOT NULL;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP;
+-- jumlah pesan belum dibaca per lawan bicara di daftar percakapan
+CREATE INDEX IF NOT EXISTS idx_messages_unread ON messages (receiver_id, sender_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS facilities (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	type facility_type NOT NULL,
+	address TEXT NOT NULL,
+	latitude DOUBLE PRECISION NOT NULL,
+	longitude DOUBLE PRECISION NOT NULL,
+	opening_hours VARCHAR(255),
+	contact VARCHAR(100),
+	blood_types TEXT[] NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_facilities_location ON facilities(latitude, longitude);
+
+CREATE TABLE IF NOT EXISTS facility_partners (
+	id SERIAL PRIMARY KEY,
+	facility_id INT NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (facility_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS facility_stocks (
+	id SERIAL PRIMARY KEY,
+	facility_id INT NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+	blood_type blood_type NOT NULL,
+	rhesus rhesus NOT NULL,
+	units INT NOT NULL,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (facility_id, blood_type, rhesus)
+);
+
+CREATE TABLE IF NOT EXISTS facility_stock_histories (
+	id SERIAL PRIMARY KEY,
+	facility_id INT NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+	blood_type blood_type NOT NULL,
+	rhesus rhesus NOT NULL,
+	units INT NOT NULL,
+	reported_by INT REFERENCES users(id) ON DELETE SET NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_facility_stock_histories_facility ON facility_stock_histories(facility_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS facility_shortages (
+	id SERIAL PRIMARY KEY,
+	facility_id INT NOT NULL REFERENCES facilities(id) ON DELETE CASCADE,
+	blood_type blood_type NOT NULL,
+	rhesus rhesus NOT NULL,
+	units INT NOT NULL,
+	notified INT NOT NULL DEFAULT 0,
+	raised_at TIMESTAMP NOT NULL,
+	resolved_at TIMESTAMP
+);
+
+-- hanya boleh ada satu shortage terbuka per golongan darah di satu fasilitas
+CREATE UNIQUE INDEX IF NOT EXISTS idx_facility_shortages_open ON facility_shortages(facility_id, blood_type, rhesus) WHERE resolved_at IS NULL;
+
 CREATE TABLE IF NOT EXISTS blood_requests (
 	id SERIAL PRIMARY KEY,
 	search_name VARCHAR(255) NOT NULL,
@@ -125,6 +315,113 @@ CREATE TABLE IF NOT EXISTS blood_requests (
 	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS user_id INT REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_blood_requests_user_id ON blood_requests(user_id);
+
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS status blood_request_status NOT NULL DEFAULT 'open';
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS deadline TIMESTAMP;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS partially_pledged_at TIMESTAMP;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS fulfilled_at TIMESTAMP;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS expired_at TIMESTAMP;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS escalation_level INT NOT NULL DEFAULT 0;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP;
+ALTER TABLE blood_requests ADD COLUMN IF NOT EXISTS facility_id INT REFERENCES facilities(id) ON DELETE SET NULL;
+
+-- request lama yang sudah tidak available dianggap dibatalkan
+UPDATE blood_requests SET status = 'cancelled' WHERE available = false AND status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_blood_requests_status_deadline ON blood_requests(status, deadline);
+
+CREATE TABLE IF NOT EXISTS pledges (
+	id SERIAL PRIMARY KEY,
+	blood_request_id INT NOT NULL REFERENCES blood_requests(id) ON DELETE CASCADE,
+	donor_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	accepted_at TIMESTAMP,
+	declined_at TIMESTAMP,
+	no_show_at TIMESTAMP,
+	donated_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (blood_request_id, donor_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pledges_blood_request_status ON pledges(blood_request_id, status);
+CREATE INDEX IF NOT EXISTS idx_pledges_donor_id ON pledges(donor_id);
+
+CREATE TABLE IF NOT EXISTS blood_request_escalations (
+	id SERIAL PRIMARY KEY,
+	blood_request_id INT NOT NULL REFERENCES blood_requests(id) ON DELETE CASCADE,
+	level INT NOT NULL,
+	action VARCHAR(50) NOT NULL,
+	notified INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_blood_request_escalations_request ON blood_request_escalations(blood_request_id);
+
+CREATE TABLE IF NOT EXISTS blood_broadcasts (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blood_request_id INT NOT NULL REFERENCES blood_requests(id) ON DELETE CASCADE,
+	title VARCHAR(255) NOT NULL,
+	body TEXT NOT NULL,
+	content_hash VARCHAR(64) NOT NULL,
+	outbox_id INT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_blood_broadcasts_user ON blood_broadcasts (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_blood_broadcasts_request ON blood_broadcasts (blood_request_id, created_at);
+
+CREATE TABLE IF NOT EXISTS messaging_preferences (
+	user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	opted_in BOOLEAN NOT NULL DEFAULT FALSE,
+	channel VARCHAR(20) NOT NULL DEFAULT 'sms',
+	opted_in_at TIMESTAMP,
+	opted_out_at TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS text_messages (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blood_request_id INT REFERENCES blood_requests(id) ON DELETE SET NULL,
+	channel VARCHAR(20) NOT NULL,
+	to_number VARCHAR(20) NOT NULL,
+	body TEXT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'queued',
+	provider_message_id VARCHAR(100),
+	error TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- satu pesan per pendonor per blood request; pesan disimpan sebelum dikirim ke gateway
+CREATE UNIQUE INDEX IF NOT EXISTS idx_text_messages_user_request_unique ON text_messages (user_id, blood_request_id) WHERE blood_request_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_text_messages_provider_id ON text_messages (provider_message_id);
+
+-- satu pengingat per pendonor per tanggal boleh donor lagi
+CREATE TABLE IF NOT EXISTS donation_reminders (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	eligible_on DATE NOT NULL,
+	push_sent BOOLEAN NOT NULL DEFAULT FALSE,
+	email_sent BOOLEAN NOT NULL DEFAULT FALSE,
+	-- pengingat yang belum sampai lewat push maupun email dicoba lagi pada next_attempt_at;
+	-- NULL berarti tidak perlu dicoba lagi
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (user_id, eligible_on)
+);
+
+CREATE INDEX IF NOT EXISTS idx_donation_reminders_retry ON donation_reminders (next_attempt_at) WHERE next_attempt_at IS NOT NULL;
+
 CREATE TABLE IF NOT EXISTS histories (
 	id SERIAL PRIMARY KEY,
 	user_id INT NOT NULL,
@@ -136,6 +433,10 @@ CREATE TABLE IF NOT EXISTS histories (
 	FOREIGN KEY (blood_request_id) REFERENCES blood_requests(id) ON DELETE CASCADE
 );
 
+ALTER TABLE histories ADD COLUMN IF NOT EXISTS image_donor TEXT;
+ALTER TABLE histories ADD COLUMN IF NOT EXISTS verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE histories ADD COLUMN IF NOT EXISTS facility_id INT REFERENCES facilities(id) ON DELETE SET NULL;
+
 CREATE INDEX IF NOT EXISTS idx_histories_user_next_donation ON histories(user_id, next_donation DESC);
 CREATE INDEX IF NOT EXISTS idx_histories_user_created_at_desc ON histories(user_id, created_at DESC);
 
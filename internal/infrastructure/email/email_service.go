@@ -2,8 +2,10 @@ package email
 
 import (
 	"fmt"
+	"html"
 	"net/smtp"
 	"strings"
+	"time"
 )
 
 type EmailService struct {
@@ -78,3 +80,30 @@ func (e *EmailService) SendVerificationEmail(to string, verificationCode string)
 
 	return e.SendEmail([]string{to}, subject, body)
 }
+
+func (e *EmailService) SendDonationReminderEmail(to, name string, eligibleOn time.Time, suggestions []string) error {
+	subject := "Kamu Sudah Bisa Donor Darah Lagi"
+
+	var items strings.Builder
+	for _, suggestion := range suggestions {
+		items.WriteString("<li>" + html.EscapeString(suggestion) + "</li>")
+	}
+
+	nearby := ""
+	if items.Len() > 0 {
+		nearby = "<p>Di sekitarmu yang membutuhkan pendonor:</p><ul>" + items.String() + "</ul>"
+	}
+
+	body := fmt.Sprintf(`
+        <html>
+            <body>
+                <h1>Halo %s,</h1>
+                <p>Masa tunggu donormu selesai. Kamu bisa donor darah lagi mulai <strong>%s</strong>.</p>
+                %s
+                <p>Buka aplikasi untuk melihat permintaan darah atau fasilitas donor terdekat.</p>
+            </body>
+        </html>
+    `, html.EscapeString(name), eligibleOn.Format("02-01-2006"), nearby)
+
+	return e.SendEmail([]string{to}, subject, body)
+}
@@ -0,0 +1,79 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPGateway mengirim SMS / WhatsApp lewat REST API provider. Provider menerima
+// POST {baseURL}/messages dengan body JSON {from, to, channel, body} dan membalas {id}.
+type HTTPGateway struct {
+	baseURL    string
+	apiKey     string
+	sender     string
+	httpClient *http.Client
+}
+
+func NewHTTPGateway(baseURL, apiKey, sender string) *HTTPGateway {
+	return &HTTPGateway{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     apiKey,
+		sender:     sender,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send implements MessagingGateway.
+func (g *HTTPGateway) Send(ctx context.Context, message Message) (string, error) {
+	payload, err := json.Marshal(map[string]string{
+		"from":    g.sender,
+		"to":      message.To,
+		"channel": string(message.Channel),
+		"body":    message.Body,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+g.apiKey)
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+		return "", fmt.Errorf("%w: %s", ErrInvalidRecipient, body)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("failed to send message (%d): %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("failed to parse messaging response: %w", err)
+	}
+
+	return result.ID, nil
+}
@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// LogGateway hanya mencatat pesan ke log tanpa menghubungi provider.
+// Dipakai untuk development lokal dan pengujian.
+type LogGateway struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewLogGateway() *LogGateway {
+	return &LogGateway{}
+}
+
+// Send implements MessagingGateway.
+func (g *LogGateway) Send(ctx context.Context, message Message) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.messages = append(g.messages, message)
+	id := fmt.Sprintf("log-%d", len(g.messages))
+
+	log.Printf("[messaging] id=%s channel=%s to=%s body=%q", id, message.Channel, message.To, message.Body)
+	return id, nil
+}
+
+// Messages mengembalikan salinan semua pesan yang sudah "dikirim"
+func (g *LogGateway) Messages() []Message {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return append([]Message(nil), g.messages...)
+}
@@ -0,0 +1,35 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+)
+
+// Channel adalah jalur pengiriman pesan teks
+type Channel string
+
+const (
+	ChannelSMS      Channel = "sms"
+	ChannelWhatsApp Channel = "whatsapp"
+)
+
+func (c Channel) IsValid() bool {
+	return c == ChannelSMS || c == ChannelWhatsApp
+}
+
+// ErrInvalidRecipient dikembalikan gateway jika nomor tujuan ditolak provider
+var ErrInvalidRecipient = errors.New("recipient number rejected by messaging provider")
+
+// Message adalah satu pesan teks ke satu nomor E.164
+type Message struct {
+	To      string
+	Channel Channel
+	Body    string
+}
+
+// MessagingGateway mendefinisikan interface untuk pengiriman SMS / WhatsApp
+type MessagingGateway interface {
+	// Send mengirim pesan dan mengembalikan id pesan dari provider, dipakai untuk
+	// mencocokkan callback status pengiriman
+	Send(ctx context.Context, message Message) (string, error)
+}
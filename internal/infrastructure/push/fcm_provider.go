@@ -0,0 +1,271 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	fcmMessagingScope  = "https://www.googleapis.com/auth/firebase.messaging"
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// FCMProvider mengirim notifikasi lewat FCM HTTP v1 API dan mengelola topic lewat Instance ID API
+type FCMProvider struct {
+	baseURL     string
+	iidURL      string
+	projectID   string
+	tokenSource oauth2.TokenSource
+	httpClient  *http.Client
+}
+
+func NewFCMProvider(credentialsFile, baseURL, iidURL string) (*FCMProvider, error) {
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fcm credentials: %w", err)
+	}
+
+	conf, err := google.JWTConfigFromJSON(data, fcmMessagingScope, cloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fcm credentials: %w", err)
+	}
+
+	// ambil project_id dari file JSON
+	var parsed struct {
+		ProjectID string `json:"project_id"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	if parsed.ProjectID == "" {
+		return nil, fmt.Errorf("project_id not found or invalid")
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	// conf.TokenSource dibuat ulang setiap refresh agar benar-benar menukar token baru,
+	// bukan mengembalikan token lama yang di-cache oleh oauth2. oauth2 tidak memakai deadline
+	// ctx untuk request token, jadi timeout diambil dari http client yang disisipkan ke ctx.
+	tokenSource := newCachedTokenSource(func(ctx context.Context) (*oauth2.Token, error) {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Timeout: tokenFetchTimeout})
+		return conf.TokenSource(ctx).Token()
+	}, tokenRefreshMargin)
+
+	return &FCMProvider{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		iidURL:      strings.TrimRight(iidURL, "/"),
+		projectID:   parsed.ProjectID,
+		tokenSource: tokenSource,
+		httpClient:  httpClient,
+	}, nil
+}
+
+// Send implements PushProvider.
+func (p *FCMProvider) Send(ctx context.Context, message Message) error {
+	msg := map[string]interface{}{
+		"notification": map[string]string{
+			"title": message.Title,
+			"body":  message.Body,
+		},
+	}
+
+	if message.Token != "" {
+		msg["token"] = message.Token
+	} else {
+		msg["topic"] = message.Topic
+	}
+
+	if len(message.Data) > 0 {
+		msg["data"] = message.Data
+	}
+
+	// Android memakai tag notifikasi, iOS memakai apns-collapse-id
+	if message.CollapseKey != "" {
+		msg["android"] = map[string]interface{}{
+			"collapse_key": message.CollapseKey,
+			"notification": map[string]string{"tag": message.CollapseKey},
+		}
+		msg["apns"] = map[string]interface{}{
+			"headers": map[string]string{"apns-collapse-id": message.CollapseKey},
+		}
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/messages:send", p.baseURL, p.projectID)
+	_, err := p.post(ctx, url, map[string]interface{}{"message": msg}, nil)
+
+	var sendErr *fcmSendError
+	if message.Token != "" && errors.As(err, &sendErr) && sendErr.invalidToken() {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, sendErr.code)
+	}
+
+	return err
+}
+
+// fcmSendError adalah respon error dari FCM HTTP v1 API
+type fcmSendError struct {
+	statusCode int
+	code       string
+	// fields berisi field request yang ditolak, diambil dari detail BadRequest
+	fields []string
+	body   string
+}
+
+func (e *fcmSendError) Error() string {
+	return fmt.Sprintf("failed to send (%d %s): %s", e.statusCode, e.code, e.body)
+}
+
+// invalidToken hanya bernilai true jika token-nya yang ditolak. INVALID_ARGUMENT juga dipakai
+// untuk payload yang salah, sehingga token baru dianggap tidak valid jika detail error
+// menunjuk field message.token.
+func (e *fcmSendError) invalidToken() bool {
+	if e.code == "UNREGISTERED" {
+		return true
+	}
+
+	if e.code != "INVALID_ARGUMENT" {
+		return false
+	}
+
+	for _, field := range e.fields {
+		if field == "message.token" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFCMError mengambil errorCode FCM dari details, atau status Google API jika tidak ada
+func parseFCMError(statusCode int, body []byte) *fcmSendError {
+	var res struct {
+		Error struct {
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode       string `json:"errorCode"`
+				FieldViolations []struct {
+					Field string `json:"field"`
+				} `json:"fieldViolations"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(body, &res)
+
+	code := res.Error.Status
+	errorCode := ""
+	var fields []string
+	for _, detail := range res.Error.Details {
+		if errorCode == "" && detail.ErrorCode != "" {
+			errorCode = detail.ErrorCode
+		}
+
+		for _, violation := range detail.FieldViolations {
+			fields = append(fields, violation.Field)
+		}
+	}
+
+	if errorCode != "" {
+		code = errorCode
+	}
+
+	return &fcmSendError{statusCode: statusCode, code: code, fields: fields, body: string(body)}
+}
+
+// SubscribeToTopic implements PushProvider.
+func (p *FCMProvider) SubscribeToTopic(ctx context.Context, tokens []string, topic string) error {
+	return p.manageTopic(ctx, "batchAdd", tokens, topic)
+}
+
+// UnsubscribeFromTopic implements PushProvider.
+func (p *FCMProvider) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) error {
+	return p.manageTopic(ctx, "batchRemove", tokens, topic)
+}
+
+func (p *FCMProvider) manageTopic(ctx context.Context, action string, tokens []string, topic string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"to":                  "/topics/" + topic,
+		"registration_tokens": tokens,
+	}
+
+	// Instance ID API butuh header ini agar menerima OAuth access token
+	headers := map[string]string{"access_token_auth": "true"}
+
+	body, err := p.post(ctx, p.iidURL+"/iid/v1:"+action, payload, headers)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Results []struct {
+			Error string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+
+	failed := 0
+	reason := ""
+	for _, result := range res.Results {
+		if result.Error != "" {
+			failed++
+			reason = result.Error
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to %s %d token(s) for topic %s: %s", action, failed, topic, reason)
+	}
+
+	return nil
+}
+
+func (p *FCMProvider) post(ctx context.Context, url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	token, err := p.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fcm access token: %w", err)
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		return nil, parseFCMError(res.StatusCode, body)
+	}
+
+	return body, nil
+}
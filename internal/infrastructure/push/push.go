@@ -0,0 +1,33 @@
+package push
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidToken dikembalikan Send jika FCM menolak token perangkat (UNREGISTERED atau
+// INVALID_ARGUMENT). Token seperti ini tidak akan pernah berhasil dan harus dihapus.
+var ErrInvalidToken = errors.New("push token is no longer valid")
+
+// Message adalah satu notifikasi yang dikirim ke satu token device atau satu topic.
+// Notifikasi dengan CollapseKey yang sama saling menggantikan di perangkat.
+type Message struct {
+	Token       string
+	Topic       string
+	Title       string
+	Body        string
+	Data        map[string]string
+	CollapseKey string
+}
+
+// PushProvider mendefinisikan interface untuk pengiriman push notification
+type PushProvider interface {
+	// Send mengirim satu message ke token atau topic
+	Send(ctx context.Context, message Message) error
+
+	// SubscribeToTopic mendaftarkan token device ke topic
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) error
+
+	// UnsubscribeFromTopic mengeluarkan token device dari topic
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) error
+}
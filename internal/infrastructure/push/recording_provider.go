@@ -0,0 +1,104 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// RecordingProvider menyimpan semua notifikasi di memori tanpa menghubungi Google.
+// Dipakai untuk development lokal dan pengujian.
+type RecordingProvider struct {
+	mu            sync.Mutex
+	messages      []Message
+	subscriptions map[string]map[string]bool
+	invalidTokens map[string]bool
+}
+
+func NewRecordingProvider() *RecordingProvider {
+	return &RecordingProvider{
+		subscriptions: make(map[string]map[string]bool),
+		invalidTokens: make(map[string]bool),
+	}
+}
+
+// Send implements PushProvider.
+func (p *RecordingProvider) Send(ctx context.Context, message Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if message.Token != "" && p.invalidTokens[message.Token] {
+		return fmt.Errorf("%w: UNREGISTERED", ErrInvalidToken)
+	}
+
+	p.messages = append(p.messages, message)
+	log.Printf("[push] token=%q topic=%q title=%q", message.Token, message.Topic, message.Title)
+	return nil
+}
+
+// SubscribeToTopic implements PushProvider.
+func (p *RecordingProvider) SubscribeToTopic(ctx context.Context, tokens []string, topic string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.subscriptions[topic] == nil {
+		p.subscriptions[topic] = make(map[string]bool)
+	}
+
+	for _, token := range tokens {
+		p.subscriptions[topic][token] = true
+	}
+	return nil
+}
+
+// UnsubscribeFromTopic implements PushProvider.
+func (p *RecordingProvider) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, token := range tokens {
+		delete(p.subscriptions[topic], token)
+	}
+	return nil
+}
+
+// MarkInvalid membuat Send ke token ini gagal seperti token yang sudah di-uninstall
+func (p *RecordingProvider) MarkInvalid(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.invalidTokens[token] = true
+}
+
+// Messages mengembalikan salinan semua message yang sudah dikirim
+func (p *RecordingProvider) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	messages := make([]Message, len(p.messages))
+	copy(messages, p.messages)
+	return messages
+}
+
+// Subscribers mengembalikan token yang sedang berlangganan topic
+func (p *RecordingProvider) Subscribers(topic string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var tokens []string
+	for token := range p.subscriptions[topic] {
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// Reset menghapus semua message dan langganan yang tercatat
+func (p *RecordingProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = nil
+	p.subscriptions = make(map[string]map[string]bool)
+	p.invalidTokens = make(map[string]bool)
+}
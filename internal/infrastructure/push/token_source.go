@@ -0,0 +1,153 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// tokenRefreshMargin adalah jeda sebelum expiry saat token sudah diperbarui
+	tokenRefreshMargin = 5 * time.Minute
+	// tokenFetchTimeout membatasi satu token exchange ke Google
+	tokenFetchTimeout = 10 * time.Second
+	// tokenRetryMaxBackoff adalah jeda terlama sebelum token exchange dicoba lagi setelah gagal
+	tokenRetryMaxBackoff = time.Minute
+)
+
+// cachedTokenSource menyimpan satu access token untuk semua pengiriman dan memperbaruinya
+// sebelum kedaluwarsa. Selama token lama masih berlaku, refresh berjalan di background sehingga
+// pengiriman tidak menunggu token exchange. fetchMu memastikan hanya satu token exchange berjalan
+// saat burst broadcast, dan setelah gagal exchange berikutnya ditunda dengan backoff.
+type cachedTokenSource struct {
+	mu         sync.Mutex
+	fetchMu    sync.Mutex
+	fetch      func(ctx context.Context) (*oauth2.Token, error)
+	margin     time.Duration
+	timeout    time.Duration
+	token      *oauth2.Token
+	refreshing bool
+	failures   int
+	lastErr    error
+	retryAt    time.Time
+}
+
+func newCachedTokenSource(fetch func(ctx context.Context) (*oauth2.Token, error), margin time.Duration) *cachedTokenSource {
+	return &cachedTokenSource{
+		fetch:   fetch,
+		margin:  margin,
+		timeout: tokenFetchTimeout,
+	}
+}
+
+// tokenRetryBackoff menghitung jeda sebelum exchange berikutnya: 1s, 2s, 4s, ... maksimal 1 menit
+func tokenRetryBackoff(failures int) time.Duration {
+	if failures < 1 {
+		return 0
+	}
+
+	if failures > 7 {
+		return tokenRetryMaxBackoff
+	}
+
+	return min(time.Second<<(failures-1), tokenRetryMaxBackoff)
+}
+
+// fresh berarti token belum masuk margin refresh
+func (s *cachedTokenSource) fresh(now time.Time) bool {
+	return s.token != nil && (s.token.Expiry.IsZero() || now.Before(s.token.Expiry.Add(-s.margin)))
+}
+
+// valid berarti token masih bisa dipakai walaupun sudah masuk margin refresh
+func (s *cachedTokenSource) valid(now time.Time) bool {
+	return s.token != nil && (s.token.Expiry.IsZero() || now.Before(s.token.Expiry))
+}
+
+// Token implements oauth2.TokenSource.
+func (s *cachedTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	now := time.Now()
+	if s.valid(now) {
+		token := s.token
+		if !s.fresh(now) && !s.refreshing && !now.Before(s.retryAt) {
+			s.refreshing = true
+			go s.refresh()
+		}
+		s.mu.Unlock()
+		return token, nil
+	}
+	s.mu.Unlock()
+
+	// tidak ada token yang berlaku: tunggu token exchange
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.Lock()
+	now = time.Now()
+	if s.valid(now) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+
+	if now.Before(s.retryAt) {
+		err := s.lastErr
+		retryAt := s.retryAt
+		s.mu.Unlock()
+		return nil, fmt.Errorf("fcm access token unavailable until %s: %w", retryAt.Format(time.RFC3339), err)
+	}
+	s.mu.Unlock()
+
+	return s.exchange()
+}
+
+// refresh memperbarui token di background selama token lama masih berlaku
+func (s *cachedTokenSource) refresh() {
+	s.fetchMu.Lock()
+	defer s.fetchMu.Unlock()
+
+	s.mu.Lock()
+	fresh := s.fresh(time.Now())
+	s.mu.Unlock()
+
+	if !fresh {
+		_, _ = s.exchange()
+	}
+
+	s.mu.Lock()
+	s.refreshing = false
+	s.mu.Unlock()
+}
+
+// exchange mengambil token baru; dipanggil dengan fetchMu terkunci
+func (s *cachedTokenSource) exchange() (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	token, err := s.fetch(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.failures++
+		s.lastErr = err
+		s.retryAt = time.Now().Add(tokenRetryBackoff(s.failures))
+		log.Printf("[push] fcm access token refresh failed (consecutive failures: %d): %v", s.failures, err)
+		return nil, err
+	}
+
+	if s.failures > 0 {
+		log.Printf("[push] fcm access token refreshed after %d failure(s)", s.failures)
+	}
+
+	s.failures = 0
+	s.lastErr = nil
+	s.retryAt = time.Time{}
+	s.token = token
+	return token, nil
+}
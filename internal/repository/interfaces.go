@@ -2,23 +2,32 @@ package repository
 
 import (
 	"backend/internal/entity"
+	"backend/pkg/bloodcompat"
 	"context"
 	"time"
 )
 
+// TxManager menjalankan beberapa operasi repository dalam satu transaksi database
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
 	FindByEmail(ctx context.Context, email string) (*entity.User, error)
 	FindById(ctx context.Context, id uint) (*entity.User, error)
 	FindByGoogleID(ctx context.Context, googleID string) (*entity.User, error)
-	FindFcmTokenByEmail(ctx context.Context, email string) (string, error)
-	UpdateFcmTokenByEmail(ctx context.Context, email, fcmToken string) error
 	Update(ctx context.Context, user *entity.User) error
 	UpdatePassword(ctx context.Context, userID uint, hashedPassword string) error
 	UpdateProfilePhoto(ctx context.Context, userID uint, photoURL string) error
 	UpdateTotalDonation(ctx context.Context, userID uint, totalDonation int) error
 	UpdateCoin(ctx context.Context, userID uint, coin int) error
 	GetCoinByUserID(ctx context.Context, userID uint) (int, error)
+	UpdateLocation(ctx context.Context, userID uint, latitude, longitude float64) error
+	FindByRole(ctx context.Context, role string) ([]*entity.User, error)
+	AddDonation(ctx context.Context, userID uint, coin int) error
+	UpdateBloodType(ctx context.Context, userID uint, bloodType, rhesus string) error
+	FindCompatibleDonorsNear(ctx context.Context, latitude, longitude, radiusKm float64, groups []bloodcompat.Group, limit int) ([]*entity.NearbyDonor, error)
 	// VerifyEmail(ctx context.Context, userID uint) error
 }
 
@@ -28,6 +37,9 @@ type TokenRepository interface {
 	Delete(ctx context.Context, id uint) error
 	FindByUserID(ctx context.Context, userID uint) ([]*entity.Token, error)
 	Exists(ctx context.Context, token string, tokenType entity.TokenType) (bool, error)
+	Consume(ctx context.Context, token string, tokenType entity.TokenType, now time.Time) (*entity.Token, error)
+	DeleteByUserID(ctx context.Context, userID uint, tokenType entity.TokenType) error
+	DeleteExpired(ctx context.Context, tokenType entity.TokenType, now time.Time) error
 }
 
 type EducationRepository interface {
@@ -56,9 +68,139 @@ type RewardRepository interface {
 }
 
 type MessageRepository interface {
-	SaveMessage(ctx context.Context, message *entity.Message) error
+	SaveMessage(ctx context.Context, message *entity.Message) (bool, error)
 	GetUndeliveredMessages(ctx context.Context, receiverID uint) ([]entity.Message, error)
 	MarkMessageAsDelivered(ctx context.Context, messageID uint) error
+	MarkReadUpTo(ctx context.Context, readerID, senderID, messageID uint, at time.Time) (int, error)
+	FindConversations(ctx context.Context, userID, before uint, limit int) ([]*entity.Conversation, error)
+	FindConversation(ctx context.Context, userID, peerID uint) (*entity.Conversation, error)
 	GetMessagesByUserID(ctx context.Context, userID1, userID2 uint, limit, offset int) ([]entity.Message, error)
-	GetLastMessage(ctx context.Context, senderID, receiverID uint) (*entity.Message, error)
+}
+
+type BloodRequestRepository interface {
+	Create(ctx context.Context, request *entity.BloodRequest) error
+	FindById(ctx context.Context, id uint) (*entity.BloodRequest, error)
+	FindByUserID(ctx context.Context, userID uint) ([]*entity.BloodRequest, error)
+	Update(ctx context.Context, request *entity.BloodRequest) error
+	UpdateStatus(ctx context.Context, id uint, from, to entity.BloodRequestStatus, at time.Time) (bool, error)
+	FindByIdForUpdate(ctx context.Context, id uint) (*entity.BloodRequest, error)
+	DecrementTotal(ctx context.Context, id uint, at time.Time) (int, error)
+	ExpireOverdue(ctx context.Context, now time.Time) ([]uint, error)
+	FindFeed(ctx context.Context, donorID uint, groups []bloodcompat.Group, latitude, longitude *float64, after *entity.FeedCursor, limit int) ([]*entity.BloodRequestFeedItem, error)
+	FindEscalationCandidates(ctx context.Context, maxLevel int) ([]*entity.BloodRequest, error)
+	ClaimEscalation(ctx context.Context, id uint, from, to int, at time.Time) (bool, error)
+	CreateEscalation(ctx context.Context, escalation *entity.BloodRequestEscalation) error
+	FindEscalations(ctx context.Context, bloodRequestID uint) ([]*entity.BloodRequestEscalation, error)
+	FindEscalationById(ctx context.Context, id uint) (*entity.BloodRequestEscalation, error)
+	UpdateEscalationNotified(ctx context.Context, id uint, notified int) error
+}
+
+type FacilityRepository interface {
+	Create(ctx context.Context, facility *entity.Facility) error
+	FindById(ctx context.Context, id uint) (*entity.Facility, error)
+	FindAll(ctx context.Context, facilityType entity.FacilityType) ([]*entity.Facility, error)
+	Update(ctx context.Context, facility *entity.Facility) error
+	Delete(ctx context.Context, id uint) error
+	FindNearest(ctx context.Context, latitude, longitude, radiusKm float64, limit int) ([]*entity.NearbyFacility, error)
+	AddPartner(ctx context.Context, facilityID, userID uint) error
+	IsPartner(ctx context.Context, facilityID, userID uint) (bool, error)
+}
+
+type FacilityStockRepository interface {
+	UpsertStock(ctx context.Context, stock *entity.FacilityStock) error
+	CreateHistory(ctx context.Context, history *entity.FacilityStockHistory) error
+	FindByFacilityID(ctx context.Context, facilityID uint) ([]*entity.FacilityStock, error)
+	FindHistory(ctx context.Context, facilityID uint, limit int) ([]*entity.FacilityStockHistory, error)
+	CreateShortage(ctx context.Context, shortage *entity.FacilityShortage) (bool, error)
+	ResolveShortage(ctx context.Context, facilityID uint, bloodType, rhesus string, at time.Time) error
+	UpdateShortageNotified(ctx context.Context, id uint, notified int) error
+}
+
+type TopicSubscriptionRepository interface {
+	FindByUserID(ctx context.Context, userID uint) ([]*entity.TopicSubscription, error)
+	FindByToken(ctx context.Context, fcmToken string) ([]*entity.TopicSubscription, error)
+	Upsert(ctx context.Context, subscription *entity.TopicSubscription) error
+	Delete(ctx context.Context, fcmToken, topic string) error
+	DeleteByToken(ctx context.Context, fcmToken string) error
+	FindUserIDsByTopics(ctx context.Context, topics []string, afterUserID uint, limit int) ([]uint, error)
+}
+
+type DeviceTokenRepository interface {
+	Upsert(ctx context.Context, token *entity.DeviceToken) (uint, error)
+	FindByUserID(ctx context.Context, userID uint) ([]*entity.DeviceToken, error)
+	FindByUserIDs(ctx context.Context, userIDs []uint) ([]*entity.DeviceToken, error)
+	Delete(ctx context.Context, userID uint, token string) (bool, error)
+	DeleteByToken(ctx context.Context, token string) error
+	MarkLegacyTopicsCleared(ctx context.Context, token string) error
+}
+
+type NotificationPreferenceRepository interface {
+	FindByUserID(ctx context.Context, userID uint) (*entity.NotificationPreference, error)
+	FindByUserIDs(ctx context.Context, userIDs []uint) ([]*entity.NotificationPreference, error)
+	Upsert(ctx context.Context, preference *entity.NotificationPreference) error
+	ReservePush(ctx context.Context, userID uint, day time.Time, limit int) (bool, error)
+	ReleasePush(ctx context.Context, userID uint, day time.Time) error
+}
+
+type BroadcastRepository interface {
+	LockUser(ctx context.Context, userID uint) error
+	Create(ctx context.Context, broadcast *entity.BloodBroadcast) error
+	CountByUserSince(ctx context.Context, userID uint, since time.Time) (int, error)
+	CountByRequestSince(ctx context.Context, bloodRequestID uint, since time.Time) (int, error)
+	ExistsSince(ctx context.Context, bloodRequestID uint, contentHash string, since time.Time) (bool, error)
+}
+
+type FeatureFlagRepository interface {
+	FindByName(ctx context.Context, name string) (*entity.FeatureFlag, error)
+	Set(ctx context.Context, flag *entity.FeatureFlag) error
+}
+
+type MessagingRepository interface {
+	FindPreference(ctx context.Context, userID uint) (*entity.MessagingPreference, error)
+	UpsertPreference(ctx context.Context, preference *entity.MessagingPreference) error
+	FindOptedInRecipients(ctx context.Context, userIDs []uint) ([]*entity.MessagingRecipient, error)
+	FindOptedInDonors(ctx context.Context, groups []bloodcompat.Group, afterUserID uint, limit int) ([]uint, error)
+	CreateMessage(ctx context.Context, message *entity.TextMessage) (bool, error)
+	FindMessageById(ctx context.Context, id uint) (*entity.TextMessage, error)
+	UpdateMessageStatus(ctx context.Context, id uint, status entity.TextMessageStatus, providerMessageID, errMessage string) error
+	UpdateStatusByProviderID(ctx context.Context, providerMessageID string, status entity.TextMessageStatus, errMessage string) (bool, error)
+}
+
+type DonationReminderRepository interface {
+	FindDue(ctx context.Context, from, to, now time.Time, maxAttempts, limit int) ([]*entity.DonationReminder, error)
+	Claim(ctx context.Context, reminder *entity.DonationReminder, now, leaseUntil time.Time) (bool, error)
+	Defer(ctx context.Context, reminder *entity.DonationReminder, until, now time.Time) error
+	MarkSent(ctx context.Context, id uint, pushSent, emailSent bool, nextAttemptAt *time.Time) error
+}
+
+type OutboxRepository interface {
+	Create(ctx context.Context, message *entity.OutboxMessage) error
+	FindById(ctx context.Context, id uint) (*entity.OutboxMessage, error)
+	FindByStatus(ctx context.Context, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error)
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*entity.OutboxMessage, error)
+	MarkSent(ctx context.Context, id uint, at time.Time) error
+	RenewLease(ctx context.Context, id uint, attempts int, leaseUntil, at time.Time) (bool, error)
+	MarkFailed(ctx context.Context, id uint, status entity.OutboxStatus, nextAttemptAt time.Time, lastError string, at time.Time) error
+	Defer(ctx context.Context, id uint, nextAttemptAt time.Time, reason string, at time.Time) error
+	Replay(ctx context.Context, id uint, at time.Time) (bool, error)
+	FindRecipients(ctx context.Context, id uint) ([]uint, error)
+	AddRecipients(ctx context.Context, id uint, userIDs []uint, at time.Time) error
+}
+
+type NotificationRepository interface {
+	CreateForUsers(ctx context.Context, userIDs []uint, notification *entity.Notification) error
+	FindByUserID(ctx context.Context, userID, before uint, limit int) ([]*entity.Notification, error)
+	MarkRead(ctx context.Context, userID, id uint, at time.Time) (bool, error)
+	MarkAllRead(ctx context.Context, userID uint, at time.Time) error
+	CountUnread(ctx context.Context, userID uint) (int, error)
+}
+
+type PledgeRepository interface {
+	Create(ctx context.Context, pledge *entity.Pledge) (bool, error)
+	FindById(ctx context.Context, id uint) (*entity.Pledge, error)
+	FindByBloodRequestAndDonor(ctx context.Context, bloodRequestID, donorID uint) (*entity.Pledge, error)
+	FindByBloodRequestID(ctx context.Context, bloodRequestID uint) ([]*entity.Pledge, error)
+	FindByDonorID(ctx context.Context, donorID uint) ([]*entity.Pledge, error)
+	UpdateStatus(ctx context.Context, id uint, from, to entity.PledgeStatus, at time.Time) (bool, error)
+	CountAccepted(ctx context.Context, bloodRequestID uint) (int, error)
 }
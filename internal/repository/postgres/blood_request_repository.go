@@ -0,0 +1,508 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"backend/pkg/bloodcompat"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type BloodRequestRepository struct {
+	db *sql.DB
+}
+
+func NewBloodRequestRepository(db *sql.DB) *BloodRequestRepository {
+	return &BloodRequestRepository{
+		db: db,
+	}
+}
+
+// pledged_count dihitung langsung dari tabel pledges agar selalu akurat
+const bloodRequestColumns = `
+	id, user_id, search_name, location, facility_id, latitude, longitude, blood_type, rhesus,
+	total, available, urgency, status,
+	(SELECT COUNT(*) FROM pledges p WHERE p.blood_request_id = blood_requests.id AND p.status = 'accepted') AS pledged_count,
+	deadline, escalation_level, escalated_at,
+	partially_pledged_at, fulfilled_at, cancelled_at, expired_at,
+	created_at, updated_at
+`
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func scanBloodRequest(row interface{ Scan(dest ...any) error }) (*entity.BloodRequest, error) {
+	br := &entity.BloodRequest{}
+	var (
+		userID             sql.NullInt64
+		facilityID         sql.NullInt64
+		deadline           sql.NullTime
+		escalatedAt        sql.NullTime
+		partiallyPledgedAt sql.NullTime
+		fulfilledAt        sql.NullTime
+		cancelledAt        sql.NullTime
+		expiredAt          sql.NullTime
+	)
+
+	err := row.Scan(
+		&br.ID,
+		&userID,
+		&br.SearchName,
+		&br.Location,
+		&facilityID,
+		&br.Latitude,
+		&br.Longitude,
+		&br.BloodType,
+		&br.Rhesus,
+		&br.Total,
+		&br.Available,
+		&br.Urgency,
+		&br.Status,
+		&br.PledgedCount,
+		&deadline,
+		&br.EscalationLevel,
+		&escalatedAt,
+		&partiallyPledgedAt,
+		&fulfilledAt,
+		&cancelledAt,
+		&expiredAt,
+		&br.CreatedAt,
+		&br.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		br.UserID = uint(userID.Int64)
+	}
+	if facilityID.Valid {
+		id := uint(facilityID.Int64)
+		br.FacilityID = &id
+	}
+	br.Deadline = nullTimePtr(deadline)
+	br.EscalatedAt = nullTimePtr(escalatedAt)
+	br.PartiallyPledgedAt = nullTimePtr(partiallyPledgedAt)
+	br.FulfilledAt = nullTimePtr(fulfilledAt)
+	br.CancelledAt = nullTimePtr(cancelledAt)
+	br.ExpiredAt = nullTimePtr(expiredAt)
+
+	return br, nil
+}
+
+// statusTimestampColumn memetakan status ke kolom timestamp transisinya
+var statusTimestampColumn = map[entity.BloodRequestStatus]string{
+	entity.BloodRequestPartiallyPledged: "partially_pledged_at",
+	entity.BloodRequestFulfilled:        "fulfilled_at",
+	entity.BloodRequestCancelled:        "cancelled_at",
+	entity.BloodRequestExpired:          "expired_at",
+}
+
+func (r *BloodRequestRepository) Create(ctx context.Context, request *entity.BloodRequest) error {
+	query := `
+	INSERT INTO blood_requests (user_id, search_name, location, facility_id, latitude, longitude, blood_type, rhesus, total, available, urgency, status, deadline, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	RETURNING id
+	`
+
+	now := time.Now()
+	request.CreatedAt = now
+	request.UpdatedAt = now
+	if request.Status == "" {
+		request.Status = entity.BloodRequestOpen
+	}
+	request.Available = request.Status.IsActive()
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		request.UserID,
+		request.SearchName,
+		request.Location,
+		request.FacilityID,
+		request.Latitude,
+		request.Longitude,
+		request.BloodType,
+		request.Rhesus,
+		request.Total,
+		request.Available,
+		request.Urgency,
+		request.Status,
+		request.Deadline,
+		request.CreatedAt,
+		request.UpdatedAt,
+	).Scan(&request.ID)
+}
+
+func (r *BloodRequestRepository) FindById(ctx context.Context, id uint) (*entity.BloodRequest, error) {
+	query := `SELECT ` + bloodRequestColumns + ` FROM blood_requests WHERE id = $1`
+
+	br, err := scanBloodRequest(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return br, nil
+}
+
+// FindByIdForUpdate mengunci baris request sampai transaksi selesai
+func (r *BloodRequestRepository) FindByIdForUpdate(ctx context.Context, id uint) (*entity.BloodRequest, error) {
+	query := `SELECT ` + bloodRequestColumns + ` FROM blood_requests WHERE id = $1 FOR UPDATE`
+
+	br, err := scanBloodRequest(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return br, nil
+}
+
+func (r *BloodRequestRepository) FindByUserID(ctx context.Context, userID uint) ([]*entity.BloodRequest, error) {
+	query := `SELECT ` + bloodRequestColumns + ` FROM blood_requests WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*entity.BloodRequest
+	for rows.Next() {
+		br, err := scanBloodRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, br)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// Update hanya mengubah data request; perubahan status lewat UpdateStatus
+func (r *BloodRequestRepository) Update(ctx context.Context, request *entity.BloodRequest) error {
+	query := `
+	UPDATE blood_requests
+	SET search_name = $1, location = $2, facility_id = $3, latitude = $4, longitude = $5, blood_type = $6, rhesus = $7,
+		total = $8, urgency = $9, deadline = $10, updated_at = $11
+	WHERE id = $12
+	`
+
+	request.UpdatedAt = time.Now()
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		request.SearchName,
+		request.Location,
+		request.FacilityID,
+		request.Latitude,
+		request.Longitude,
+		request.BloodType,
+		request.Rhesus,
+		request.Total,
+		request.Urgency,
+		request.Deadline,
+		request.UpdatedAt,
+		request.ID,
+	)
+
+	return err
+}
+
+// UpdateStatus memindahkan status dari `from` ke `to` dan mencatat waktu transisinya.
+// Mengembalikan false jika status di database sudah bukan `from` (diubah proses lain).
+func (r *BloodRequestRepository) UpdateStatus(ctx context.Context, id uint, from, to entity.BloodRequestStatus, at time.Time) (bool, error) {
+	query := `
+	UPDATE blood_requests
+	SET status = $1, available = $2, updated_at = $3
+	WHERE id = $4 AND status = $5
+	`
+	if column, ok := statusTimestampColumn[to]; ok {
+		query = `
+	UPDATE blood_requests
+	SET status = $1, available = $2, updated_at = $3, ` + column + ` = $3
+	WHERE id = $4 AND status = $5
+	`
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, to, to.IsActive(), at, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// DecrementTotal mengurangi sisa kebutuhan kantong darah dan mengembalikan sisanya
+func (r *BloodRequestRepository) DecrementTotal(ctx context.Context, id uint, at time.Time) (int, error) {
+	query := `
+	UPDATE blood_requests
+	SET total = total - 1, updated_at = $1
+	WHERE id = $2 AND total > 0
+	RETURNING total
+	`
+
+	var remaining int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, at, id).Scan(&remaining)
+	return remaining, err
+}
+
+// ExpireOverdue menandai semua request aktif yang melewati deadline sebagai expired
+func (r *BloodRequestRepository) ExpireOverdue(ctx context.Context, now time.Time) ([]uint, error) {
+	query := `
+	UPDATE blood_requests
+	SET status = 'expired', available = false, expired_at = $1, updated_at = $1
+	WHERE status IN ('open', 'partially_pledged') AND deadline IS NOT NULL AND deadline < $1
+	RETURNING id
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// FindEscalationCandidates mengambil request aktif yang belum punya pledge sama sekali
+// dan belum mencapai level eskalasi maxLevel
+func (r *BloodRequestRepository) FindEscalationCandidates(ctx context.Context, maxLevel int) ([]*entity.BloodRequest, error) {
+	query := `SELECT ` + bloodRequestColumns + ` FROM blood_requests
+	WHERE status = 'open' AND escalation_level < $1
+		AND NOT EXISTS (SELECT 1 FROM pledges p WHERE p.blood_request_id = blood_requests.id)
+	ORDER BY urgency DESC, created_at ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, maxLevel)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*entity.BloodRequest
+	for rows.Next() {
+		br, err := scanBloodRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, br)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// ClaimEscalation menaikkan level eskalasi dari `from` ke `to`.
+// Mengembalikan false jika level sudah diubah proses lain.
+func (r *BloodRequestRepository) ClaimEscalation(ctx context.Context, id uint, from, to int, at time.Time) (bool, error) {
+	query := `
+	UPDATE blood_requests
+	SET escalation_level = $1, escalated_at = $2, updated_at = $2
+	WHERE id = $3 AND escalation_level = $4
+	`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, to, at, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *BloodRequestRepository) CreateEscalation(ctx context.Context, escalation *entity.BloodRequestEscalation) error {
+	query := `
+	INSERT INTO blood_request_escalations (blood_request_id, level, action, notified, created_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id
+	`
+
+	escalation.CreatedAt = time.Now()
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		escalation.BloodRequestID,
+		escalation.Level,
+		escalation.Action,
+		escalation.Notified,
+		escalation.CreatedAt,
+	).Scan(&escalation.ID)
+}
+
+func (r *BloodRequestRepository) FindEscalations(ctx context.Context, bloodRequestID uint) ([]*entity.BloodRequestEscalation, error) {
+	query := `
+	SELECT id, blood_request_id, level, action, notified, created_at
+	FROM blood_request_escalations
+	WHERE blood_request_id = $1
+	ORDER BY level ASC
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, bloodRequestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var escalations []*entity.BloodRequestEscalation
+	for rows.Next() {
+		e := &entity.BloodRequestEscalation{}
+		if err := rows.Scan(&e.ID, &e.BloodRequestID, &e.Level, &e.Action, &e.Notified, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		escalations = append(escalations, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return escalations, nil
+}
+
+func (r *BloodRequestRepository) FindEscalationById(ctx context.Context, id uint) (*entity.BloodRequestEscalation, error) {
+	query := `
+	SELECT id, blood_request_id, level, action, notified, created_at
+	FROM blood_request_escalations
+	WHERE id = $1
+	`
+
+	e := &entity.BloodRequestEscalation{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(&e.ID, &e.BloodRequestID, &e.Level, &e.Action, &e.Notified, &e.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// UpdateEscalationNotified mencatat jumlah penerima setelah notifikasi eskalasi dikirim outbox
+func (r *BloodRequestRepository) UpdateEscalationNotified(ctx context.Context, id uint, notified int) error {
+	query := `
+	UPDATE blood_request_escalations SET notified = $2 WHERE id = $1
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, notified)
+	return err
+}
+
+// scannerWithExtra menambahkan kolom tambahan di belakang kolom blood request
+type scannerWithExtra struct {
+	row   interface{ Scan(dest ...any) error }
+	extra []any
+}
+
+func (s scannerWithExtra) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
+
+// FindFeed mengambil request aktif yang bisa dibantu pendonor dengan golongan `groups`,
+// diurutkan berdasarkan urgency lalu jarak dari (latitude, longitude), dengan keyset pagination.
+// Request tanpa koordinat ditaruh paling akhir pada urgency yang sama.
+func (r *BloodRequestRepository) FindFeed(ctx context.Context, donorID uint, groups []bloodcompat.Group, latitude, longitude *float64, after *entity.FeedCursor, limit int) ([]*entity.BloodRequestFeedItem, error) {
+	query := `
+	SELECT * FROM (
+		SELECT ` + bloodRequestColumns + `, distance_km,
+			CASE WHEN $1::float8 IS NULL THEN 0 ELSE COALESCE(distance_km, 1000000000) END AS sort_distance
+		FROM (
+			SELECT *,
+				CASE WHEN $1::float8 IS NULL OR latitude IS NULL OR longitude IS NULL THEN NULL
+				ELSE ` + haversineKm("latitude", "longitude") + ` END AS distance_km
+			FROM blood_requests
+			WHERE status IN ('open', 'partially_pledged')
+				AND (deadline IS NULL OR deadline > NOW())
+				AND user_id IS DISTINCT FROM $3
+				AND (blood_type || ':' || rhesus) = ANY($4)
+		) blood_requests
+	) feed
+	WHERE $5::int IS NULL
+		OR urgency < $5
+		OR (urgency = $5 AND sort_distance > $6)
+		OR (urgency = $5 AND sort_distance = $6 AND id > $7)
+	ORDER BY urgency DESC, sort_distance ASC, id ASC
+	LIMIT $8
+	`
+
+	keys := make([]string, 0, len(groups))
+	for _, g := range groups {
+		keys = append(keys, g.BloodType+":"+g.Rhesus)
+	}
+
+	var afterUrgency, afterDistance, afterID any
+	if after != nil {
+		afterUrgency, afterDistance, afterID = after.Urgency, after.DistanceKm, after.ID
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, latitude, longitude, donorID, pq.Array(keys), afterUrgency, afterDistance, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*entity.BloodRequestFeedItem
+	for rows.Next() {
+		item := &entity.BloodRequestFeedItem{}
+		var distance sql.NullFloat64
+
+		br, err := scanBloodRequest(scannerWithExtra{row: rows, extra: []any{&distance, &item.SortDistance}})
+		if err != nil {
+			return nil, err
+		}
+
+		item.BloodRequest = *br
+		if distance.Valid {
+			item.DistanceKm = &distance.Float64
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type BroadcastRepository struct {
+	db *sql.DB
+}
+
+func NewBroadcastRepository(db *sql.DB) *BroadcastRepository {
+	return &BroadcastRepository{
+		db: db,
+	}
+}
+
+// broadcastLockNamespace membedakan advisory lock broadcast dari advisory lock lain
+const broadcastLockNamespace = 1001
+
+// LockUser mengunci rate limit broadcast milik user sampai transaksi selesai, sehingga
+// broadcast paralel untuk request berbeda tidak bisa melewati batas per user.
+// Harus dipanggil di dalam TxManager.WithinTx.
+func (r *BroadcastRepository) LockUser(ctx context.Context, userID uint) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, `SELECT pg_advisory_xact_lock($1, $2)`, broadcastLockNamespace, int32(userID))
+	return err
+}
+
+func (r *BroadcastRepository) Create(ctx context.Context, broadcast *entity.BloodBroadcast) error {
+	query := `
+	INSERT INTO blood_broadcasts (user_id, blood_request_id, title, body, content_hash, outbox_id, created_at)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7)
+	RETURNING id
+	`
+
+	broadcast.CreatedAt = time.Now()
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		broadcast.UserID,
+		broadcast.BloodRequestID,
+		broadcast.Title,
+		broadcast.Body,
+		broadcast.ContentHash,
+		broadcast.OutboxID,
+		broadcast.CreatedAt,
+	).Scan(&broadcast.ID)
+}
+
+// CountByUserSince menghitung broadcast milik user sejak since, untuk semua request
+func (r *BroadcastRepository) CountByUserSince(ctx context.Context, userID uint, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM blood_broadcasts WHERE user_id = $1 AND created_at >= $2`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, since).Scan(&count)
+	return count, err
+}
+
+func (r *BroadcastRepository) CountByRequestSince(ctx context.Context, bloodRequestID uint, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM blood_broadcasts WHERE blood_request_id = $1 AND created_at >= $2`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, bloodRequestID, since).Scan(&count)
+	return count, err
+}
+
+// ExistsSince mengecek apakah broadcast dengan isi yang sama untuk request ini sudah dikirim sejak since
+func (r *BroadcastRepository) ExistsSince(ctx context.Context, bloodRequestID uint, contentHash string, since time.Time) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1 FROM blood_broadcasts
+		WHERE blood_request_id = $1 AND content_hash = $2 AND created_at >= $3
+	)
+	`
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, bloodRequestID, contentHash, since).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type DeviceTokenRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceTokenRepository(db *sql.DB) *DeviceTokenRepository {
+	return &DeviceTokenRepository{
+		db: db,
+	}
+}
+
+const deviceTokenColumns = `id, user_id, token, platform, COALESCE(app_version, ''), last_seen_at, created_at, legacy_topics_cleared_at IS NOT NULL`
+
+func scanDeviceTokens(rows *sql.Rows) ([]*entity.DeviceToken, error) {
+	defer rows.Close()
+
+	var tokens []*entity.DeviceToken
+	for rows.Next() {
+		t := &entity.DeviceToken{}
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Token, &t.Platform, &t.AppVersion, &t.LastSeenAt, &t.CreatedAt, &t.LegacyTopicsCleared); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Upsert mendaftarkan token perangkat. Token yang sama dipindahkan ke user terakhir yang login
+// di perangkat tersebut, sehingga notifikasi user lama tidak masuk ke perangkat itu.
+// Mengembalikan pemilik sebelumnya jika token berpindah user, atau 0 jika tidak.
+func (r *DeviceTokenRepository) Upsert(ctx context.Context, token *entity.DeviceToken) (uint, error) {
+	query := `
+	WITH previous AS (
+		SELECT user_id FROM device_tokens WHERE token = $2
+	)
+	INSERT INTO device_tokens (user_id, token, platform, app_version, last_seen_at, created_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5, $5)
+	ON CONFLICT (token) DO UPDATE SET
+		user_id = EXCLUDED.user_id,
+		platform = CASE WHEN EXCLUDED.platform = 'unknown' THEN device_tokens.platform ELSE EXCLUDED.platform END,
+		app_version = COALESCE(EXCLUDED.app_version, device_tokens.app_version),
+		last_seen_at = EXCLUDED.last_seen_at
+	RETURNING id, platform, COALESCE(app_version, ''), created_at, COALESCE((SELECT user_id FROM previous), 0)
+	`
+
+	token.LastSeenAt = time.Now()
+
+	var previousUserID uint
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		token.UserID,
+		token.Token,
+		token.Platform,
+		token.AppVersion,
+		token.LastSeenAt,
+	).Scan(&token.ID, &token.Platform, &token.AppVersion, &token.CreatedAt, &previousUserID)
+	if err != nil {
+		return 0, err
+	}
+
+	if previousUserID == token.UserID {
+		return 0, nil
+	}
+	return previousUserID, nil
+}
+
+func (r *DeviceTokenRepository) FindByUserID(ctx context.Context, userID uint) ([]*entity.DeviceToken, error) {
+	query := `SELECT ` + deviceTokenColumns + ` FROM device_tokens WHERE user_id = $1 ORDER BY last_seen_at DESC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeviceTokens(rows)
+}
+
+// FindByUserIDs mengambil token semua perangkat milik banyak user sekaligus untuk broadcast
+func (r *DeviceTokenRepository) FindByUserIDs(ctx context.Context, userIDs []uint) ([]*entity.DeviceToken, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = int64(userID)
+	}
+
+	query := `SELECT ` + deviceTokenColumns + ` FROM device_tokens WHERE user_id = ANY($1::int[])`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	return scanDeviceTokens(rows)
+}
+
+// Delete mengembalikan false jika token tidak terdaftar untuk user tersebut
+func (r *DeviceTokenRepository) Delete(ctx context.Context, userID uint, token string) (bool, error) {
+	query := `DELETE FROM device_tokens WHERE user_id = $1 AND token = $2`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, userID, token)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// DeleteByToken dipakai untuk membuang token yang ditolak FCM
+func (r *DeviceTokenRepository) DeleteByToken(ctx context.Context, token string) error {
+	query := `DELETE FROM device_tokens WHERE token = $1`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, token)
+	return err
+}
+
+// MarkLegacyTopicsCleared mencatat bahwa token sudah di-unsubscribe dari topic lama
+func (r *DeviceTokenRepository) MarkLegacyTopicsCleared(ctx context.Context, token string) error {
+	query := `UPDATE device_tokens SET legacy_topics_cleared_at = $1 WHERE token = $2`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), token)
+	return err
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type DonationReminderRepository struct {
+	db *sql.DB
+}
+
+func NewDonationReminderRepository(db *sql.DB) *DonationReminderRepository {
+	return &DonationReminderRepository{
+		db: db,
+	}
+}
+
+// FindDue mengembalikan pendonor yang tanggal boleh donor terakhirnya (dari riwayat yang sudah
+// diverifikasi) berada di antara from dan to (inklusif) dan belum diingatkan untuk tanggal tersebut,
+// ditambah pengingat yang belum sampai atau ditunda dan sudah waktunya dicoba lagi pada now
+func (r *DonationReminderRepository) FindDue(ctx context.Context, from, to, now time.Time, maxAttempts, limit int) ([]*entity.DonationReminder, error) {
+	query := `
+	SELECT h.user_id, h.next_donation, COALESCE(d.attempts, 0)
+	FROM (
+		SELECT user_id, MAX(next_donation) AS next_donation
+		FROM histories
+		WHERE verified
+		GROUP BY user_id
+	) h
+	JOIN users u ON u.id = h.user_id
+	LEFT JOIN donation_reminders d ON d.user_id = h.user_id AND d.eligible_on = h.next_donation
+	WHERE u.role = 'pendonor'
+		AND h.next_donation <= $2::date
+		AND (
+			(d.id IS NULL AND h.next_donation >= $1::date)
+			OR (NOT d.push_sent AND NOT d.email_sent AND d.attempts < $4 AND d.next_attempt_at <= $3)
+		)
+	ORDER BY h.next_donation, h.user_id
+	LIMIT $5
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, from.Format("2006-01-02"), to.Format("2006-01-02"), now, maxAttempts, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []*entity.DonationReminder
+	for rows.Next() {
+		reminder := &entity.DonationReminder{}
+		if err := rows.Scan(&reminder.UserID, &reminder.EligibleOn, &reminder.Attempts); err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
+
+// Claim mencatat percobaan pengiriman pengingat dan menahannya sampai leaseUntil. false jika
+// pengingat sudah terkirim atau sedang dikirim, misalnya oleh instance lain atau sebelum restart.
+func (r *DonationReminderRepository) Claim(ctx context.Context, reminder *entity.DonationReminder, now, leaseUntil time.Time) (bool, error) {
+	query := `
+	INSERT INTO donation_reminders (user_id, eligible_on, attempts, next_attempt_at, created_at)
+	VALUES ($1, $2::date, 1, $4, $3)
+	ON CONFLICT (user_id, eligible_on) DO UPDATE
+	SET attempts = donation_reminders.attempts + 1, next_attempt_at = EXCLUDED.next_attempt_at
+	WHERE NOT donation_reminders.push_sent AND NOT donation_reminders.email_sent
+		AND donation_reminders.next_attempt_at <= $3
+	RETURNING id, attempts, created_at
+	`
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, reminder.UserID, reminder.EligibleOn.Format("2006-01-02"), now, leaseUntil).Scan(&reminder.ID, &reminder.Attempts, &reminder.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	reminder.NextAttemptAt = &leaseUntil
+	return true, nil
+}
+
+// Defer menunda pengingat sampai until tanpa menghitungnya sebagai percobaan, misalnya selama
+// quiet hours user, agar tidak terus diambil FindDue dan menghabiskan batch
+func (r *DonationReminderRepository) Defer(ctx context.Context, reminder *entity.DonationReminder, until, now time.Time) error {
+	query := `
+	INSERT INTO donation_reminders (user_id, eligible_on, attempts, next_attempt_at, created_at)
+	VALUES ($1, $2::date, 0, $3, $4)
+	ON CONFLICT (user_id, eligible_on) DO UPDATE
+	SET next_attempt_at = EXCLUDED.next_attempt_at
+	WHERE NOT donation_reminders.push_sent AND NOT donation_reminders.email_sent
+		AND donation_reminders.next_attempt_at <= $4
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, reminder.UserID, reminder.EligibleOn.Format("2006-01-02"), until, now)
+	return err
+}
+
+// MarkSent mencatat hasil pengiriman. nextAttemptAt nil berarti pengingat tidak dicoba lagi.
+func (r *DonationReminderRepository) MarkSent(ctx context.Context, id uint, pushSent, emailSent bool, nextAttemptAt *time.Time) error {
+	query := `
+	UPDATE donation_reminders
+	SET push_sent = $2, email_sent = $3, next_attempt_at = $4
+	WHERE id = $1
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, pushSent, emailSent, nextAttemptAt)
+	return err
+}
@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type FacilityRepository struct {
+	db *sql.DB
+}
+
+func NewFacilityRepository(db *sql.DB) *FacilityRepository {
+	return &FacilityRepository{
+		db: db,
+	}
+}
+
+const facilityColumns = `
+	id, name, type, address, latitude, longitude,
+	COALESCE(opening_hours, ''), COALESCE(contact, ''), blood_types,
+	created_at, updated_at
+`
+
+func scanFacility(row interface{ Scan(dest ...any) error }) (*entity.Facility, error) {
+	f := &entity.Facility{}
+
+	err := row.Scan(
+		&f.ID,
+		&f.Name,
+		&f.Type,
+		&f.Address,
+		&f.Latitude,
+		&f.Longitude,
+		&f.OpeningHours,
+		&f.Contact,
+		pq.Array(&f.BloodTypes),
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (r *FacilityRepository) Create(ctx context.Context, facility *entity.Facility) error {
+	query := `
+	INSERT INTO facilities (name, type, address, latitude, longitude, opening_hours, contact, blood_types, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id
+	`
+
+	now := time.Now()
+	facility.CreatedAt = now
+	facility.UpdatedAt = now
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		facility.Name,
+		facility.Type,
+		facility.Address,
+		facility.Latitude,
+		facility.Longitude,
+		facility.OpeningHours,
+		facility.Contact,
+		pq.Array(facility.BloodTypes),
+		facility.CreatedAt,
+		facility.UpdatedAt,
+	).Scan(&facility.ID)
+}
+
+func (r *FacilityRepository) FindById(ctx context.Context, id uint) (*entity.Facility, error) {
+	query := `SELECT ` + facilityColumns + ` FROM facilities WHERE id = $1`
+
+	f, err := scanFacility(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// FindAll mengambil semua fasilitas, difilter berdasarkan tipe jika facilityType tidak kosong
+func (r *FacilityRepository) FindAll(ctx context.Context, facilityType entity.FacilityType) ([]*entity.Facility, error) {
+	query := `SELECT ` + facilityColumns + ` FROM facilities WHERE ($1 = '' OR type::text = $1) ORDER BY name ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, string(facilityType))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var facilities []*entity.Facility
+	for rows.Next() {
+		f, err := scanFacility(rows)
+		if err != nil {
+			return nil, err
+		}
+		facilities = append(facilities, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return facilities, nil
+}
+
+func (r *FacilityRepository) Update(ctx context.Context, facility *entity.Facility) error {
+	query := `
+	UPDATE facilities
+	SET name = $1, type = $2, address = $3, latitude = $4, longitude = $5,
+		opening_hours = $6, contact = $7, blood_types = $8, updated_at = $9
+	WHERE id = $10
+	`
+
+	facility.UpdatedAt = time.Now()
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		facility.Name,
+		facility.Type,
+		facility.Address,
+		facility.Latitude,
+		facility.Longitude,
+		facility.OpeningHours,
+		facility.Contact,
+		pq.Array(facility.BloodTypes),
+		facility.UpdatedAt,
+		facility.ID,
+	)
+
+	return err
+}
+
+func (r *FacilityRepository) Delete(ctx context.Context, id uint) error {
+	query := `DELETE FROM facilities WHERE id = $1`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id)
+	return err
+}
+
+// FindNearest mencari fasilitas dalam radius radiusKm, diurutkan dari yang terdekat
+func (r *FacilityRepository) FindNearest(ctx context.Context, latitude, longitude, radiusKm float64, limit int) ([]*entity.NearbyFacility, error) {
+	query := `
+	SELECT * FROM (
+		SELECT ` + facilityColumns + `,
+			` + haversineKm("latitude", "longitude") + ` AS distance_km
+		FROM facilities
+		WHERE latitude BETWEEN $1 - ($3 / 111.0) AND $1 + ($3 / 111.0)
+	) f
+	WHERE distance_km <= $3
+	ORDER BY distance_km ASC
+	LIMIT $4
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, latitude, longitude, radiusKm, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var facilities []*entity.NearbyFacility
+	for rows.Next() {
+		nf := &entity.NearbyFacility{}
+
+		f, err := scanFacility(scannerWithExtra{row: rows, extra: []any{&nf.DistanceKm}})
+		if err != nil {
+			return nil, err
+		}
+
+		nf.Facility = *f
+		facilities = append(facilities, nf)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return facilities, nil
+}
+
+func (r *FacilityRepository) AddPartner(ctx context.Context, facilityID, userID uint) error {
+	query := `
+	INSERT INTO facility_partners (facility_id, user_id, created_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (facility_id, user_id) DO NOTHING
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, facilityID, userID, time.Now())
+	return err
+}
+
+// IsPartner menandakan user adalah petugas yang boleh melapor stok untuk fasilitas tersebut
+func (r *FacilityRepository) IsPartner(ctx context.Context, facilityID, userID uint) (bool, error) {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM facility_partners WHERE facility_id = $1 AND user_id = $2)
+	`
+
+	var exists bool
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, facilityID, userID).Scan(&exists)
+	return exists, err
+}
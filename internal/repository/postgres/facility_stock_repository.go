@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type FacilityStockRepository struct {
+	db *sql.DB
+}
+
+func NewFacilityStockRepository(db *sql.DB) *FacilityStockRepository {
+	return &FacilityStockRepository{
+		db: db,
+	}
+}
+
+// UpsertStock menyimpan jumlah stok terbaru untuk satu golongan darah
+func (r *FacilityStockRepository) UpsertStock(ctx context.Context, stock *entity.FacilityStock) error {
+	query := `
+	INSERT INTO facility_stocks (facility_id, blood_type, rhesus, units, updated_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (facility_id, blood_type, rhesus)
+	DO UPDATE SET units = EXCLUDED.units, updated_at = EXCLUDED.updated_at
+	`
+
+	stock.UpdatedAt = time.Now()
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		stock.FacilityID,
+		stock.BloodType,
+		stock.Rhesus,
+		stock.Units,
+		stock.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r *FacilityStockRepository) CreateHistory(ctx context.Context, history *entity.FacilityStockHistory) error {
+	query := `
+	INSERT INTO facility_stock_histories (facility_id, blood_type, rhesus, units, reported_by, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id
+	`
+
+	history.CreatedAt = time.Now()
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		history.FacilityID,
+		history.BloodType,
+		history.Rhesus,
+		history.Units,
+		history.ReportedBy,
+		history.CreatedAt,
+	).Scan(&history.ID)
+}
+
+func (r *FacilityStockRepository) FindByFacilityID(ctx context.Context, facilityID uint) ([]*entity.FacilityStock, error) {
+	query := `
+	SELECT s.facility_id, s.blood_type, s.rhesus, s.units,
+		EXISTS (
+			SELECT 1 FROM facility_shortages sh
+			WHERE sh.facility_id = s.facility_id AND sh.blood_type = s.blood_type
+				AND sh.rhesus = s.rhesus AND sh.resolved_at IS NULL
+		) AS shortage,
+		s.updated_at
+	FROM facility_stocks s
+	WHERE s.facility_id = $1
+	ORDER BY s.blood_type, s.rhesus
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, facilityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocks []*entity.FacilityStock
+	for rows.Next() {
+		s := &entity.FacilityStock{}
+		if err := rows.Scan(&s.FacilityID, &s.BloodType, &s.Rhesus, &s.Units, &s.Shortage, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stocks, nil
+}
+
+func (r *FacilityStockRepository) FindHistory(ctx context.Context, facilityID uint, limit int) ([]*entity.FacilityStockHistory, error) {
+	query := `
+	SELECT id, facility_id, blood_type, rhesus, units, reported_by, created_at
+	FROM facility_stock_histories
+	WHERE facility_id = $1
+	ORDER BY created_at DESC
+	LIMIT $2
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, facilityID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var histories []*entity.FacilityStockHistory
+	for rows.Next() {
+		h := &entity.FacilityStockHistory{}
+		var reportedBy sql.NullInt64
+		if err := rows.Scan(&h.ID, &h.FacilityID, &h.BloodType, &h.Rhesus, &h.Units, &reportedBy, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		if reportedBy.Valid {
+			id := uint(reportedBy.Int64)
+			h.ReportedBy = &id
+		}
+		histories = append(histories, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return histories, nil
+}
+
+// CreateShortage membuka shortage baru. Mengembalikan false jika shortage untuk golongan
+// darah yang sama masih terbuka sehingga pendonor tidak dinotifikasi berulang kali.
+func (r *FacilityStockRepository) CreateShortage(ctx context.Context, shortage *entity.FacilityShortage) (bool, error) {
+	query := `
+	INSERT INTO facility_shortages (facility_id, blood_type, rhesus, units, raised_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (facility_id, blood_type, rhesus) WHERE resolved_at IS NULL DO NOTHING
+	RETURNING id
+	`
+
+	shortage.RaisedAt = time.Now()
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		shortage.FacilityID,
+		shortage.BloodType,
+		shortage.Rhesus,
+		shortage.Units,
+		shortage.RaisedAt,
+	).Scan(&shortage.ID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ResolveShortage menutup shortage terbuka setelah stok kembali di atas threshold
+func (r *FacilityStockRepository) ResolveShortage(ctx context.Context, facilityID uint, bloodType, rhesus string, at time.Time) error {
+	query := `
+	UPDATE facility_shortages
+	SET resolved_at = $1
+	WHERE facility_id = $2 AND blood_type = $3 AND rhesus = $4 AND resolved_at IS NULL
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, at, facilityID, bloodType, rhesus)
+	return err
+}
+
+func (r *FacilityStockRepository) UpdateShortageNotified(ctx context.Context, id uint, notified int) error {
+	query := `UPDATE facility_shortages SET notified = $1 WHERE id = $2`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, notified, id)
+	return err
+}
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type FeatureFlagRepository struct {
+	db *sql.DB
+}
+
+func NewFeatureFlagRepository(db *sql.DB) *FeatureFlagRepository {
+	return &FeatureFlagRepository{
+		db: db,
+	}
+}
+
+// FindByName mengembalikan flag nonaktif jika belum pernah disimpan
+func (r *FeatureFlagRepository) FindByName(ctx context.Context, name string) (*entity.FeatureFlag, error) {
+	query := `SELECT name, enabled, updated_by, updated_at FROM feature_flags WHERE name = $1`
+
+	flag := &entity.FeatureFlag{}
+	var updatedBy sql.NullInt64
+
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, name).Scan(&flag.Name, &flag.Enabled, &updatedBy, &flag.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &entity.FeatureFlag{Name: name}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if updatedBy.Valid {
+		id := uint(updatedBy.Int64)
+		flag.UpdatedBy = &id
+	}
+
+	return flag, nil
+}
+
+func (r *FeatureFlagRepository) Set(ctx context.Context, flag *entity.FeatureFlag) error {
+	query := `
+	INSERT INTO feature_flags (name, enabled, updated_by, updated_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (name) DO UPDATE SET
+		enabled = EXCLUDED.enabled,
+		updated_by = EXCLUDED.updated_by,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	flag.UpdatedAt = time.Now()
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, flag.Name, flag.Enabled, flag.UpdatedBy, flag.UpdatedAt)
+	return err
+}
@@ -0,0 +1,12 @@
+package postgres
+
+// haversineKm adalah ekspresi SQL jarak (km) antara kolom latColumn/lngColumn dan titik $1/$2.
+// Argumen ASIN dibatasi 1 karena galat floating point bisa sedikit melewatinya dan
+// membuat ASIN gagal untuk titik yang berhimpitan atau berseberangan.
+func haversineKm(latColumn, lngColumn string) string {
+	return `2 * 6371 * ASIN(LEAST(1, SQRT(
+		POWER(SIN(RADIANS(` + latColumn + ` - $1) / 2), 2) +
+		COS(RADIANS($1)) * COS(RADIANS(` + latColumn + `)) *
+		POWER(SIN(RADIANS(` + lngColumn + ` - $2) / 2), 2)
+	)))`
+}
@@ -20,23 +20,27 @@ func NewHistoryRepository(db *sql.DB) *HistoryRepository {
 
 func (r *HistoryRepository) Create(ctx context.Context, history *entity.History) error {
 	query := `
-	INSERT INTO histories (user_id, blood_request_id, next_donation, created_at, updated_at) 
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO histories (user_id, blood_request_id, facility_id, image_donor, verified, next_donation, created_at, updated_at) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id
 	`
 
 	now := time.Now()
 	history.CreatedAt = now
 	history.UpdatedAt = now
 
-	_, err := r.db.ExecContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx,
 		query,
 		history.UserID,
 		history.BloodRequestID,
+		history.FacilityID,
+		history.ImageDonor,
+		history.Verified,
 		history.NextDonation,
 		history.CreatedAt,
 		history.UpdatedAt,
-	)
+	).Scan(&history.ID)
 
 	if err != nil {
 		return err
@@ -47,7 +51,8 @@ func (r *HistoryRepository) Create(ctx context.Context, history *entity.History)
 
 func (r *HistoryRepository) GetByUserID(ctx context.Context, id uint) ([]*entity.History, error) {
 	query := `
-	SELECT * FROM histories WHERE user_id = $1;
+	SELECT id, user_id, blood_request_id, facility_id, COALESCE(image_donor, ''), verified, next_donation, created_at, updated_at
+	FROM histories WHERE user_id = $1;
 	`
 
 	rows, err := r.db.QueryContext(ctx, query, id)
@@ -60,11 +65,14 @@ func (r *HistoryRepository) GetByUserID(ctx context.Context, id uint) ([]*entity
 
 	for rows.Next() {
 		history := &entity.History{}
+		var facilityID sql.NullInt64
 		err := rows.Scan(
 			&history.ID,
 			&history.UserID,
 			&history.BloodRequestID,
+			&facilityID,
 			&history.ImageDonor,
+			&history.Verified,
 			&history.NextDonation,
 			&history.CreatedAt,
 			&history.UpdatedAt,
@@ -72,6 +80,10 @@ func (r *HistoryRepository) GetByUserID(ctx context.Context, id uint) ([]*entity
 		if err != nil {
 			return nil, err
 		}
+		if facilityID.Valid {
+			id := uint(facilityID.Int64)
+			history.FacilityID = &id
+		}
 		histories = append(histories, history)
 	}
 
@@ -17,10 +17,13 @@ func NewMessageRepository(db *sql.DB) *MessageRepository {
 	}
 }
 
-func (r *MessageRepository) SaveMessage(ctx context.Context, message *entity.Message) error {
+// SaveMessage menyimpan pesan dan mengisi ID-nya. Jika ClientMsgID yang sama sudah pernah
+// dikirim pengirim yang sama, pesan lama dimuat ke message dan dikembalikan false.
+func (r *MessageRepository) SaveMessage(ctx context.Context, message *entity.Message) (bool, error) {
 	query := `
-	INSERT INTO messages (sender_id, receiver_id, content, is_delivered, created_at, updated_at)
-	VALUES ($1, $2, $3, $4, $5, $6)
+	INSERT INTO messages (sender_id, receiver_id, content, client_msg_id, is_delivered, created_at, updated_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+	ON CONFLICT (sender_id, client_msg_id) WHERE client_msg_id IS NOT NULL DO NOTHING
 	RETURNING id;
 	`
 
@@ -29,31 +32,67 @@ func (r *MessageRepository) SaveMessage(ctx context.Context, message *entity.Mes
 	message.UpdatedAt = now
 
 	// Default is_delivered to false (message not delivered yet)
-	isDelivered := false
+	message.IsDelivered = false
 
 	var id uint
-	err := r.db.QueryRowContext(
+	err := conn(ctx, r.db).QueryRowContext(
 		ctx, query,
 		message.SenderID,
 		message.ReceiverID,
 		message.Content,
-		isDelivered,
+		message.ClientMsgID,
+		message.IsDelivered,
 		message.CreatedAt,
 		message.UpdatedAt,
 	).Scan(&id)
 
+	if err == sql.ErrNoRows {
+		existing, err := r.findByClientMsgID(ctx, message.SenderID, message.ClientMsgID)
+		if err != nil {
+			return false, err
+		}
+
+		*message = *existing
+		return false, nil
+	}
+
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	message.ID = id
-	return nil
+	return true, nil
+}
+
+func (r *MessageRepository) findByClientMsgID(ctx context.Context, senderID uint, clientMsgID string) (*entity.Message, error) {
+	query := `
+	SELECT id, sender_id, receiver_id, content, COALESCE(client_msg_id, ''), COALESCE(is_delivered, false), created_at, updated_at
+	FROM messages
+	WHERE sender_id = $1 AND client_msg_id = $2
+	`
+
+	var msg entity.Message
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, senderID, clientMsgID).Scan(
+		&msg.ID,
+		&msg.SenderID,
+		&msg.ReceiverID,
+		&msg.Content,
+		&msg.ClientMsgID,
+		&msg.IsDelivered,
+		&msg.CreatedAt,
+		&msg.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
 }
 
 // GetUndeliveredMessages retrieves all undelivered messages for a specific receiver
 func (r *MessageRepository) GetUndeliveredMessages(ctx context.Context, receiverID uint) ([]entity.Message, error) {
 	query := `
-	SELECT id, sender_id, receiver_id, content, created_at, updated_at
+	SELECT id, sender_id, receiver_id, content, COALESCE(client_msg_id, ''), created_at, updated_at
 	FROM messages
 	WHERE receiver_id = $1 AND is_delivered = false
 	ORDER BY created_at ASC
@@ -73,6 +112,7 @@ func (r *MessageRepository) GetUndeliveredMessages(ctx context.Context, receiver
 			&msg.SenderID,
 			&msg.ReceiverID,
 			&msg.Content,
+			&msg.ClientMsgID,
 			&msg.CreatedAt,
 			&msg.UpdatedAt,
 		); err != nil {
@@ -100,10 +140,32 @@ func (r *MessageRepository) MarkMessageAsDelivered(ctx context.Context, messageI
 	return err
 }
 
+// MarkReadUpTo menandai semua pesan dari senderID ke readerID sampai messageID sebagai dibaca.
+// Pesan yang dibaca otomatis dianggap sudah terkirim.
+func (r *MessageRepository) MarkReadUpTo(ctx context.Context, readerID, senderID, messageID uint, at time.Time) (int, error) {
+	query := `
+	UPDATE messages
+	SET read_at = $4, is_delivered = true, updated_at = $4
+	WHERE receiver_id = $1 AND sender_id = $2 AND id <= $3 AND read_at IS NULL
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, readerID, senderID, messageID, at)
+	if err != nil {
+		return 0, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(affected), nil
+}
+
 // GetMessagesByUserID retrieves messages between two users
 func (r *MessageRepository) GetMessagesByUserID(ctx context.Context, userID1, userID2 uint, limit, offset int) ([]entity.Message, error) {
 	query := `
-	SELECT id, sender_id, receiver_id, content, is_delivered, created_at, updated_at
+	SELECT id, sender_id, receiver_id, content, COALESCE(client_msg_id, ''), COALESCE(is_delivered, false), read_at, created_at, updated_at
 	FROM messages
 	WHERE (sender_id = $1 AND receiver_id = $2) OR (sender_id = $2 AND receiver_id = $1)
 	ORDER BY created_at DESC
@@ -119,14 +181,15 @@ func (r *MessageRepository) GetMessagesByUserID(ctx context.Context, userID1, us
 	var messages []entity.Message
 	for rows.Next() {
 		var msg entity.Message
-		var isDelivered bool
 
 		if err := rows.Scan(
 			&msg.ID,
 			&msg.SenderID,
 			&msg.ReceiverID,
 			&msg.Content,
-			&isDelivered,
+			&msg.ClientMsgID,
+			&msg.IsDelivered,
+			&msg.ReadAt,
 			&msg.CreatedAt,
 			&msg.UpdatedAt,
 		); err != nil {
@@ -143,33 +206,81 @@ func (r *MessageRepository) GetMessagesByUserID(ctx context.Context, userID1, us
 	return messages, nil
 }
 
-// Tambahkan method GetLastMessage pada MessageRepository
-func (r *MessageRepository) GetLastMessage(ctx context.Context, senderID, receiverID uint) (*entity.Message, error) {
-	query := `
-	SELECT id, sender_id, receiver_id, content, is_delivered, created_at, updated_at
-	FROM messages
-	WHERE sender_id = $1 AND receiver_id = $2
-	ORDER BY created_at DESC
-	LIMIT 1
-	`
+// conversationsQuery mengambil pesan terakhir per lawan bicara $1. $2 adalah cursor
+// last_message_id, $3 membatasi ke satu lawan bicara dan disaring sebelum DISTINCT ON agar
+// hanya pesan dengan lawan bicara tersebut yang dibaca.
+const conversationsQuery = `
+	SELECT last.peer_id, u.name, u.profile_photo, last.id, last.sender_id, last.content, last.created_at,
+		(
+			SELECT COUNT(*) FROM messages unread
+			WHERE unread.receiver_id = $1 AND unread.sender_id = last.peer_id AND unread.read_at IS NULL
+		) AS unread_count
+	FROM (
+		SELECT DISTINCT ON (peer_id) peer_id, id, sender_id, content, created_at
+		FROM (
+			SELECT CASE WHEN sender_id = $1 THEN receiver_id ELSE sender_id END AS peer_id,
+				id, sender_id, content, created_at
+			FROM messages
+			WHERE ($3::int IS NULL AND (sender_id = $1 OR receiver_id = $1))
+				OR (sender_id = $1 AND receiver_id = $3)
+				OR (sender_id = $3 AND receiver_id = $1)
+		) m
+		ORDER BY peer_id, id DESC
+	) last
+	JOIN users u ON u.id = last.peer_id
+	WHERE $2::int IS NULL OR last.id < $2
+	ORDER BY last.id DESC
+	LIMIT $4
+`
 
-	var msg entity.Message
-	var isDelivered bool
+func (r *MessageRepository) queryConversations(ctx context.Context, userID uint, before, peerID any, limit int) ([]*entity.Conversation, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, conversationsQuery, userID, before, peerID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	err := r.db.QueryRowContext(ctx, query, senderID, receiverID).Scan(
-		&msg.ID,
-		&msg.SenderID,
-		&msg.ReceiverID,
-		&msg.Content,
-		&isDelivered,
-		&msg.CreatedAt,
-		&msg.UpdatedAt,
-	)
+	var conversations []*entity.Conversation
+	for rows.Next() {
+		c := &entity.Conversation{}
+		if err := rows.Scan(
+			&c.PeerID,
+			&c.PeerName,
+			&c.PeerPhoto,
+			&c.LastMessageID,
+			&c.LastSenderID,
+			&c.LastMessage,
+			&c.LastMessageAt,
+			&c.UnreadCount,
+		); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, c)
+	}
+
+	return conversations, rows.Err()
+}
 
+// FindConversations mengembalikan percakapan userID dari yang terbaru. before = 0 berarti halaman pertama.
+func (r *MessageRepository) FindConversations(ctx context.Context, userID, before uint, limit int) ([]*entity.Conversation, error) {
+	var cursor any
+	if before > 0 {
+		cursor = before
+	}
+
+	return r.queryConversations(ctx, userID, cursor, nil, limit)
+}
+
+// FindConversation mengembalikan nil jika userID belum pernah chat dengan peerID
+func (r *MessageRepository) FindConversation(ctx context.Context, userID, peerID uint) (*entity.Conversation, error) {
+	conversations, err := r.queryConversations(ctx, userID, nil, peerID, 1)
 	if err != nil {
 		return nil, err
 	}
 
-	msg.IsDelivered = isDelivered
-	return &msg, nil
-}
\ No newline at end of file
+	if len(conversations) == 0 {
+		return nil, nil
+	}
+
+	return conversations[0], nil
+}
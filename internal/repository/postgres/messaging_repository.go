@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"backend/pkg/bloodcompat"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type MessagingRepository struct {
+	db *sql.DB
+}
+
+func NewMessagingRepository(db *sql.DB) *MessagingRepository {
+	return &MessagingRepository{
+		db: db,
+	}
+}
+
+// FindPreference mengembalikan nil jika user belum pernah mengatur SMS / WhatsApp
+func (r *MessagingRepository) FindPreference(ctx context.Context, userID uint) (*entity.MessagingPreference, error) {
+	query := `
+	SELECT user_id, opted_in, channel, opted_in_at, opted_out_at, updated_at
+	FROM messaging_preferences
+	WHERE user_id = $1
+	`
+
+	p := &entity.MessagingPreference{}
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(
+		&p.UserID,
+		&p.OptedIn,
+		&p.Channel,
+		&p.OptedInAt,
+		&p.OptedOutAt,
+		&p.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *MessagingRepository) UpsertPreference(ctx context.Context, p *entity.MessagingPreference) error {
+	query := `
+	INSERT INTO messaging_preferences (user_id, opted_in, channel, opted_in_at, opted_out_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (user_id) DO UPDATE SET
+		opted_in = EXCLUDED.opted_in,
+		channel = EXCLUDED.channel,
+		opted_in_at = EXCLUDED.opted_in_at,
+		opted_out_at = EXCLUDED.opted_out_at,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	p.UpdatedAt = time.Now()
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, p.UserID, p.OptedIn, p.Channel, p.OptedInAt, p.OptedOutAt, p.UpdatedAt)
+	return err
+}
+
+// FindOptedInRecipients mengembalikan pendonor dari userIDs yang sudah opt-in beserta nomor teleponnya
+func (r *MessagingRepository) FindOptedInRecipients(ctx context.Context, userIDs []uint) ([]*entity.MessagingRecipient, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = int64(userID)
+	}
+
+	query := `
+	SELECT u.id, mp.channel, u.phone_number
+	FROM messaging_preferences mp
+	JOIN users u ON u.id = mp.user_id
+	WHERE mp.user_id = ANY($1) AND mp.opted_in = TRUE AND u.role = 'pendonor' AND u.phone_number <> ''
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*entity.MessagingRecipient
+	for rows.Next() {
+		recipient := &entity.MessagingRecipient{}
+		if err := rows.Scan(&recipient.UserID, &recipient.Channel, &recipient.PhoneNumber); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// FindOptedInDonors mengambil satu halaman pendonor yang opt-in SMS / WhatsApp dengan golongan
+// darah groups, urut user_id setelah afterUserID, termasuk yang tidak punya perangkat untuk push
+func (r *MessagingRepository) FindOptedInDonors(ctx context.Context, groups []bloodcompat.Group, afterUserID uint, limit int) ([]uint, error) {
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(groups))
+	for _, g := range groups {
+		keys = append(keys, g.BloodType+":"+g.Rhesus)
+	}
+
+	query := `
+	SELECT u.id
+	FROM messaging_preferences mp
+	JOIN users u ON u.id = mp.user_id
+	WHERE mp.opted_in = TRUE AND u.role = 'pendonor' AND u.phone_number <> ''
+		AND (u.blood_type || ':' || u.rhesus) = ANY($1) AND u.id > $2
+	ORDER BY u.id
+	LIMIT $3
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(keys), afterUserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uint
+	for rows.Next() {
+		var userID uint
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// CreateMessage mencatat pesan sebelum dikirim. Mengembalikan false jika pendonor sudah pernah
+// dikirimi pesan untuk blood request yang sama.
+func (r *MessagingRepository) CreateMessage(ctx context.Context, message *entity.TextMessage) (bool, error) {
+	query := `
+	INSERT INTO text_messages (user_id, blood_request_id, channel, to_number, body, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+	ON CONFLICT (user_id, blood_request_id) WHERE blood_request_id IS NOT NULL DO NOTHING
+	RETURNING id
+	`
+
+	message.CreatedAt = time.Now()
+	message.UpdatedAt = message.CreatedAt
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		message.UserID,
+		message.BloodRequestID,
+		message.Channel,
+		message.ToNumber,
+		message.Body,
+		message.Status,
+		message.CreatedAt,
+	).Scan(&message.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *MessagingRepository) FindMessageById(ctx context.Context, id uint) (*entity.TextMessage, error) {
+	query := `
+	SELECT id, user_id, blood_request_id, channel, to_number, body, status,
+		COALESCE(provider_message_id, ''), COALESCE(error, ''), created_at, updated_at
+	FROM text_messages
+	WHERE id = $1
+	`
+
+	m := &entity.TextMessage{}
+	var bloodRequestID sql.NullInt64
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, id).Scan(
+		&m.ID,
+		&m.UserID,
+		&bloodRequestID,
+		&m.Channel,
+		&m.ToNumber,
+		&m.Body,
+		&m.Status,
+		&m.ProviderMessageID,
+		&m.Error,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bloodRequestID.Valid {
+		id := uint(bloodRequestID.Int64)
+		m.BloodRequestID = &id
+	}
+
+	return m, nil
+}
+
+// UpdateMessageStatus mencatat hasil pengiriman ke gateway
+func (r *MessagingRepository) UpdateMessageStatus(ctx context.Context, id uint, status entity.TextMessageStatus, providerMessageID, errMessage string) error {
+	query := `
+	UPDATE text_messages
+	SET status = $2, provider_message_id = NULLIF($3, ''), error = NULLIF($4, ''), updated_at = $5
+	WHERE id = $1
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, status, providerMessageID, errMessage, time.Now())
+	return err
+}
+
+// UpdateStatusByProviderID dipakai callback status dari provider
+func (r *MessagingRepository) UpdateStatusByProviderID(ctx context.Context, providerMessageID string, status entity.TextMessageStatus, errMessage string) (bool, error) {
+	query := `
+	UPDATE text_messages
+	SET status = $2, error = NULLIF($3, ''), updated_at = $4
+	WHERE provider_message_id = $1
+	`
+
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, providerMessageID, status, errMessage, time.Now())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type NotificationPreferenceRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationPreferenceRepository(db *sql.DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{
+		db: db,
+	}
+}
+
+const notificationPreferenceColumns = `user_id, urgent_requests, matching_requests, chat, reminders, rewards, education,
+	max_per_day, COALESCE(quiet_hours_start, ''), COALESCE(quiet_hours_end, ''), timezone, updated_at`
+
+func scanNotificationPreference(row interface{ Scan(dest ...any) error }) (*entity.NotificationPreference, error) {
+	p := &entity.NotificationPreference{}
+	err := row.Scan(
+		&p.UserID,
+		&p.UrgentRequests,
+		&p.MatchingRequests,
+		&p.Chat,
+		&p.Reminders,
+		&p.Rewards,
+		&p.Education,
+		&p.MaxPerDay,
+		&p.QuietHoursStart,
+		&p.QuietHoursEnd,
+		&p.Timezone,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// FindByUserID mengembalikan nil jika user belum menyimpan preferensi
+func (r *NotificationPreferenceRepository) FindByUserID(ctx context.Context, userID uint) (*entity.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences WHERE user_id = $1`
+
+	p, err := scanNotificationPreference(conn(ctx, r.db).QueryRowContext(ctx, query, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// FindByUserIDs hanya mengembalikan user yang sudah menyimpan preferensi
+func (r *NotificationPreferenceRepository) FindByUserIDs(ctx context.Context, userIDs []uint) ([]*entity.NotificationPreference, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int64, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = int64(userID)
+	}
+
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences WHERE user_id = ANY($1)`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var preferences []*entity.NotificationPreference
+	for rows.Next() {
+		p, err := scanNotificationPreference(rows)
+		if err != nil {
+			return nil, err
+		}
+		preferences = append(preferences, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (r *NotificationPreferenceRepository) Upsert(ctx context.Context, p *entity.NotificationPreference) error {
+	query := `
+	INSERT INTO notification_preferences (
+		user_id, urgent_requests, matching_requests, chat, reminders, rewards, education,
+		max_per_day, quiet_hours_start, quiet_hours_end, timezone, updated_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), $11, $12)
+	ON CONFLICT (user_id) DO UPDATE SET
+		urgent_requests = EXCLUDED.urgent_requests,
+		matching_requests = EXCLUDED.matching_requests,
+		chat = EXCLUDED.chat,
+		reminders = EXCLUDED.reminders,
+		rewards = EXCLUDED.rewards,
+		education = EXCLUDED.education,
+		max_per_day = EXCLUDED.max_per_day,
+		quiet_hours_start = EXCLUDED.quiet_hours_start,
+		quiet_hours_end = EXCLUDED.quiet_hours_end,
+		timezone = EXCLUDED.timezone,
+		updated_at = EXCLUDED.updated_at
+	`
+
+	p.UpdatedAt = time.Now()
+
+	_, err := conn(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		p.UserID,
+		p.UrgentRequests,
+		p.MatchingRequests,
+		p.Chat,
+		p.Reminders,
+		p.Rewards,
+		p.Education,
+		p.MaxPerDay,
+		p.QuietHoursStart,
+		p.QuietHoursEnd,
+		p.Timezone,
+		p.UpdatedAt,
+	)
+
+	return err
+}
+
+// ReservePush menambah hitungan push user pada day jika belum mencapai limit.
+// Cek dan penambahan dilakukan dalam satu query agar aman dari pengiriman paralel.
+func (r *NotificationPreferenceRepository) ReservePush(ctx context.Context, userID uint, day time.Time, limit int) (bool, error) {
+	query := `
+	INSERT INTO notification_push_counts (user_id, day, count)
+	VALUES ($1, $2, 1)
+	ON CONFLICT (user_id, day) DO UPDATE SET count = notification_push_counts.count + 1
+	WHERE notification_push_counts.count < $3
+	RETURNING count
+	`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID, day.Format("2006-01-02"), limit).Scan(&count)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReleasePush mengembalikan satu jatah push user pada day, dipakai jika push yang sudah
+// dihitung ReservePush ternyata gagal terkirim
+func (r *NotificationPreferenceRepository) ReleasePush(ctx context.Context, userID uint, day time.Time) error {
+	query := `
+	UPDATE notification_push_counts SET count = count - 1
+	WHERE user_id = $1 AND day = $2 AND count > 0
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID, day.Format("2006-01-02"))
+	return err
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type NotificationRepository struct {
+	db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) *NotificationRepository {
+	return &NotificationRepository{
+		db: db,
+	}
+}
+
+// CreateForUsers menyimpan notifikasi yang sama ke inbox banyak user dalam satu query,
+// dipakai untuk broadcast ke topic maupun ke pendonor di sekitar lokasi
+func (r *NotificationRepository) CreateForUsers(ctx context.Context, userIDs []uint, notification *entity.Notification) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(notification.Payload)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = int64(userID)
+	}
+
+	query := `
+	INSERT INTO notifications (user_id, type, title, body, payload, target, created_at)
+	SELECT DISTINCT u.id, $2, $3, $4, $5::jsonb, NULLIF($6, ''), $7
+	FROM unnest($1::int[]) AS u(id)
+	`
+
+	notification.CreatedAt = time.Now()
+
+	_, err = conn(ctx, r.db).ExecContext(
+		ctx,
+		query,
+		pq.Array(ids),
+		notification.Type,
+		notification.Title,
+		notification.Body,
+		string(payload),
+		notification.Target,
+		notification.CreatedAt,
+	)
+
+	return err
+}
+
+// FindByUserID mengambil notifikasi terbaru; before adalah id notifikasi terakhir halaman sebelumnya
+func (r *NotificationRepository) FindByUserID(ctx context.Context, userID, before uint, limit int) ([]*entity.Notification, error) {
+	query := `
+	SELECT id, user_id, type, title, body, payload, COALESCE(target, ''), read_at, created_at
+	FROM notifications
+	WHERE user_id = $1 AND ($2 = 0 OR id < $2)
+	ORDER BY id DESC
+	LIMIT $3
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*entity.Notification
+	for rows.Next() {
+		n := &entity.Notification{}
+		var payload []byte
+
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &payload, &n.Target, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(payload, &n.Payload); err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// MarkRead mengembalikan false jika notifikasi tidak ditemukan atau bukan milik user
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id uint, at time.Time) (bool, error) {
+	query := `
+	UPDATE notifications SET read_at = COALESCE(read_at, $3)
+	WHERE id = $1 AND user_id = $2
+	`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, userID, at)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uint, at time.Time) error {
+	query := `UPDATE notifications SET read_at = $2 WHERE user_id = $1 AND read_at IS NULL`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, userID, at)
+	return err
+}
+
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uint) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
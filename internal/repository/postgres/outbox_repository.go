@@ -0,0 +1,272 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
+
+const outboxColumns = `
+	id, kind, payload, status, attempts, next_attempt_at,
+	COALESCE(last_error, ''), created_at, updated_at, sent_at
+`
+
+func scanOutboxMessage(row interface{ Scan(dest ...any) error }) (*entity.OutboxMessage, error) {
+	m := &entity.OutboxMessage{}
+
+	err := row.Scan(
+		&m.ID,
+		&m.Kind,
+		&m.Payload,
+		&m.Status,
+		&m.Attempts,
+		&m.NextAttemptAt,
+		&m.LastError,
+		&m.CreatedAt,
+		&m.UpdatedAt,
+		&m.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func scanOutboxMessages(rows *sql.Rows) ([]*entity.OutboxMessage, error) {
+	defer rows.Close()
+
+	var messages []*entity.OutboxMessage
+	for rows.Next() {
+		m, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// Create ditulis lewat conn agar ikut transaksi perubahan data yang memicu notifikasi
+func (r *OutboxRepository) Create(ctx context.Context, message *entity.OutboxMessage) error {
+	query := `
+	INSERT INTO notification_outbox (kind, payload, status, attempts, next_attempt_at, created_at, updated_at)
+	VALUES ($1, $2, $3, 0, $4, $4, $4)
+	RETURNING id
+	`
+
+	now := time.Now()
+	message.Status = entity.OutboxPending
+	message.NextAttemptAt = now
+	message.CreatedAt = now
+	message.UpdatedAt = now
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		message.Kind,
+		[]byte(message.Payload),
+		message.Status,
+		now,
+	).Scan(&message.ID)
+}
+
+func (r *OutboxRepository) FindById(ctx context.Context, id uint) (*entity.OutboxMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM notification_outbox WHERE id = $1`
+
+	m, err := scanOutboxMessage(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (r *OutboxRepository) FindByStatus(ctx context.Context, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error) {
+	query := `
+	SELECT ` + outboxColumns + `
+	FROM notification_outbox
+	WHERE status = $1
+	ORDER BY updated_at DESC
+	LIMIT $2
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, status, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxMessages(rows)
+}
+
+// ClaimDue mengambil message yang sudah waktunya dikirim dan memundurkan next_attempt_at
+// sampai leaseUntil. Jika worker mati di tengah pengiriman, message akan diambil lagi
+// setelah lease habis. SKIP LOCKED mencegah dua instance mengambil message yang sama.
+func (r *OutboxRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*entity.OutboxMessage, error) {
+	query := `
+	UPDATE notification_outbox
+	SET attempts = attempts + 1, next_attempt_at = $2, updated_at = $1
+	WHERE id IN (
+		SELECT id FROM notification_outbox
+		WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + outboxColumns
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanOutboxMessages(rows)
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id uint, at time.Time) error {
+	query := `
+	UPDATE notification_outbox
+	SET status = 'sent', sent_at = $2, updated_at = $2, last_error = NULL
+	WHERE id = $1
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, at)
+	return err
+}
+
+// RenewLease memperpanjang lease message yang akan dikirim. attempts adalah nilai saat message
+// diambil; jika lease sudah habis dan message diambil worker lain, attempts sudah bertambah
+// sehingga lease tidak diperpanjang dan message tidak dikirim dua kali.
+func (r *OutboxRepository) RenewLease(ctx context.Context, id uint, attempts int, leaseUntil, at time.Time) (bool, error) {
+	query := `
+	UPDATE notification_outbox
+	SET next_attempt_at = $3, updated_at = $4
+	WHERE id = $1 AND attempts = $2 AND status = 'pending'
+	`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, attempts, leaseUntil, at)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint, status entity.OutboxStatus, nextAttemptAt time.Time, lastError string, at time.Time) error {
+	query := `
+	UPDATE notification_outbox
+	SET status = $2, next_attempt_at = $3, last_error = $4, updated_at = $5
+	WHERE id = $1
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, status, nextAttemptAt, lastError, at)
+	return err
+}
+
+// Defer menunda message tanpa menghabiskan jatah percobaan, dipakai saat push ditahan
+// preferensi user dan bukan karena pengiriman gagal
+func (r *OutboxRepository) Defer(ctx context.Context, id uint, nextAttemptAt time.Time, reason string, at time.Time) error {
+	query := `
+	UPDATE notification_outbox
+	SET attempts = GREATEST(attempts - 1, 0), next_attempt_at = $2, last_error = $3, updated_at = $4
+	WHERE id = $1
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, nextAttemptAt, reason, at)
+	return err
+}
+
+// Replay mengembalikan message dead ke antrean dengan jatah percobaan baru
+func (r *OutboxRepository) Replay(ctx context.Context, id uint, at time.Time) (bool, error) {
+	query := `
+	UPDATE notification_outbox
+	SET status = 'pending', attempts = 0, next_attempt_at = $2, updated_at = $2
+	WHERE id = $1 AND status = 'dead'
+	`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, id, at)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// FindRecipients mengambil user yang sudah dijangkau oleh message multi-penerima
+func (r *OutboxRepository) FindRecipients(ctx context.Context, id uint) ([]uint, error) {
+	query := `SELECT user_id FROM notification_outbox_recipients WHERE outbox_id = $1`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uint
+	for rows.Next() {
+		var userID uint
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
+
+// AddRecipients mencatat user yang sudah dijangkau; user yang sudah tercatat diabaikan
+func (r *OutboxRepository) AddRecipients(ctx context.Context, id uint, userIDs []uint, at time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = int64(userID)
+	}
+
+	query := `
+	INSERT INTO notification_outbox_recipients (outbox_id, user_id, reached_at)
+	SELECT $1, unnest($2::int[]), $3
+	ON CONFLICT (outbox_id, user_id) DO NOTHING
+	`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, id, pq.Array(ids), at)
+	return err
+}
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PledgeRepository struct {
+	db *sql.DB
+}
+
+func NewPledgeRepository(db *sql.DB) *PledgeRepository {
+	return &PledgeRepository{
+		db: db,
+	}
+}
+
+const pledgeColumns = `
+	id, blood_request_id, donor_id, status, accepted_at, declined_at, no_show_at, donated_at, created_at, updated_at
+`
+
+// pledgeTimestampColumn memetakan status pledge ke kolom timestamp transisinya
+var pledgeTimestampColumn = map[entity.PledgeStatus]string{
+	entity.PledgeAccepted: "accepted_at",
+	entity.PledgeDeclined: "declined_at",
+	entity.PledgeNoShow:   "no_show_at",
+	entity.PledgeDonated:  "donated_at",
+}
+
+func scanPledge(row interface{ Scan(dest ...any) error }) (*entity.Pledge, error) {
+	p := &entity.Pledge{}
+	var acceptedAt, declinedAt, noShowAt, donatedAt sql.NullTime
+
+	err := row.Scan(
+		&p.ID,
+		&p.BloodRequestID,
+		&p.DonorID,
+		&p.Status,
+		&acceptedAt,
+		&declinedAt,
+		&noShowAt,
+		&donatedAt,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	p.AcceptedAt = nullTimePtr(acceptedAt)
+	p.DeclinedAt = nullTimePtr(declinedAt)
+	p.NoShowAt = nullTimePtr(noShowAt)
+	p.DonatedAt = nullTimePtr(donatedAt)
+
+	return p, nil
+}
+
+func (r *PledgeRepository) queryPledges(ctx context.Context, query string, args ...any) ([]*entity.Pledge, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pledges []*entity.Pledge
+	for rows.Next() {
+		p, err := scanPledge(rows)
+		if err != nil {
+			return nil, err
+		}
+		pledges = append(pledges, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pledges, nil
+}
+
+// Create menyimpan pledge baru. Mengembalikan false jika donor sudah pernah pledge ke request yang sama.
+func (r *PledgeRepository) Create(ctx context.Context, pledge *entity.Pledge) (bool, error) {
+	query := `
+	INSERT INTO pledges (blood_request_id, donor_id, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (blood_request_id, donor_id) DO NOTHING
+	RETURNING id
+	`
+
+	now := time.Now()
+	pledge.CreatedAt = now
+	pledge.UpdatedAt = now
+
+	err := conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		pledge.BloodRequestID,
+		pledge.DonorID,
+		pledge.Status,
+		pledge.CreatedAt,
+		pledge.UpdatedAt,
+	).Scan(&pledge.ID)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *PledgeRepository) FindById(ctx context.Context, id uint) (*entity.Pledge, error) {
+	query := `SELECT ` + pledgeColumns + ` FROM pledges WHERE id = $1`
+
+	p, err := scanPledge(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *PledgeRepository) FindByBloodRequestAndDonor(ctx context.Context, bloodRequestID, donorID uint) (*entity.Pledge, error) {
+	query := `SELECT ` + pledgeColumns + ` FROM pledges WHERE blood_request_id = $1 AND donor_id = $2`
+
+	p, err := scanPledge(conn(ctx, r.db).QueryRowContext(ctx, query, bloodRequestID, donorID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *PledgeRepository) FindByBloodRequestID(ctx context.Context, bloodRequestID uint) ([]*entity.Pledge, error) {
+	query := `SELECT ` + pledgeColumns + ` FROM pledges WHERE blood_request_id = $1 ORDER BY created_at ASC`
+	return r.queryPledges(ctx, query, bloodRequestID)
+}
+
+func (r *PledgeRepository) FindByDonorID(ctx context.Context, donorID uint) ([]*entity.Pledge, error) {
+	query := `SELECT ` + pledgeColumns + ` FROM pledges WHERE donor_id = $1 ORDER BY created_at DESC`
+	return r.queryPledges(ctx, query, donorID)
+}
+
+// UpdateStatus memindahkan status pledge dari `from` ke `to`.
+// Mengembalikan false jika status di database sudah bukan `from`.
+func (r *PledgeRepository) UpdateStatus(ctx context.Context, id uint, from, to entity.PledgeStatus, at time.Time) (bool, error) {
+	query := `
+	UPDATE pledges
+	SET status = $1, updated_at = $2
+	WHERE id = $3 AND status = $4
+	`
+	if column, ok := pledgeTimestampColumn[to]; ok {
+		query = `
+	UPDATE pledges
+	SET status = $1, updated_at = $2, ` + column + ` = $2
+	WHERE id = $3 AND status = $4
+	`
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, to, at, id, from)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+func (r *PledgeRepository) CountAccepted(ctx context.Context, bloodRequestID uint) (int, error) {
+	query := `
+	SELECT COUNT(*) FROM pledges WHERE blood_request_id = $1 AND status = 'accepted';
+	`
+
+	var count int
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, bloodRequestID).Scan(&count)
+	return count, err
+}
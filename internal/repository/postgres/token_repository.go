@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 )
 
 type TokenRepository struct {
@@ -127,3 +128,53 @@ func (r *TokenRepository) Exists(ctx context.Context, token string, tokenType en
 
 	return exists, nil
 }
+
+// Consume menghapus token yang belum kedaluwarsa pada now lalu mengembalikannya, sehingga
+// token hanya bisa dipakai sekali walaupun ditukar bersamaan
+func (r *TokenRepository) Consume(ctx context.Context, token string, tokenType entity.TokenType, now time.Time) (*entity.Token, error) {
+	query := `
+	DELETE FROM tokens
+	WHERE token = $1 AND type = $2 AND expires_at > $3
+	RETURNING id, user_id, token, type, expires_at, created_at
+	`
+
+	t := &entity.Token{}
+	err := r.db.QueryRowContext(ctx, query, token, tokenType, now).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Token,
+		&t.Type,
+		&t.ExpiresAt,
+		&t.CreatedAt,
+	)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (r *TokenRepository) DeleteByUserID(ctx context.Context, userID uint, tokenType entity.TokenType) error {
+	query := `
+	DELETE FROM tokens WHERE user_id = $1 AND type = $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, tokenType)
+
+	return err
+}
+
+// DeleteExpired menghapus token bertipe tokenType yang sudah kedaluwarsa pada now
+func (r *TokenRepository) DeleteExpired(ctx context.Context, tokenType entity.TokenType, now time.Time) error {
+	query := `
+	DELETE FROM tokens WHERE type = $1 AND expires_at <= $2
+	`
+
+	_, err := r.db.ExecContext(ctx, query, tokenType, now)
+
+	return err
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"backend/internal/entity"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TopicSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewTopicSubscriptionRepository(db *sql.DB) *TopicSubscriptionRepository {
+	return &TopicSubscriptionRepository{
+		db: db,
+	}
+}
+
+func (r *TopicSubscriptionRepository) querySubscriptions(ctx context.Context, query string, args ...any) ([]*entity.TopicSubscription, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*entity.TopicSubscription
+	for rows.Next() {
+		s := &entity.TopicSubscription{}
+		if err := rows.Scan(&s.ID, &s.UserID, &s.FCMToken, &s.Topic, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *TopicSubscriptionRepository) FindByUserID(ctx context.Context, userID uint) ([]*entity.TopicSubscription, error) {
+	query := `
+	SELECT id, user_id, fcm_token, topic, created_at
+	FROM topic_subscriptions
+	WHERE user_id = $1
+	`
+
+	return r.querySubscriptions(ctx, query, userID)
+}
+
+// FindByToken mengambil semua langganan satu perangkat, apa pun pemiliknya
+func (r *TopicSubscriptionRepository) FindByToken(ctx context.Context, fcmToken string) ([]*entity.TopicSubscription, error) {
+	query := `
+	SELECT id, user_id, fcm_token, topic, created_at
+	FROM topic_subscriptions
+	WHERE fcm_token = $1
+	`
+
+	return r.querySubscriptions(ctx, query, fcmToken)
+}
+
+// Upsert menyimpan langganan topic satu perangkat; user diperbarui jika perangkat berpindah akun
+func (r *TopicSubscriptionRepository) Upsert(ctx context.Context, subscription *entity.TopicSubscription) error {
+	query := `
+	INSERT INTO topic_subscriptions (user_id, fcm_token, topic, created_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (fcm_token, topic) DO UPDATE SET user_id = EXCLUDED.user_id
+	RETURNING id
+	`
+
+	subscription.CreatedAt = time.Now()
+
+	return conn(ctx, r.db).QueryRowContext(
+		ctx,
+		query,
+		subscription.UserID,
+		subscription.FCMToken,
+		subscription.Topic,
+		subscription.CreatedAt,
+	).Scan(&subscription.ID)
+}
+
+func (r *TopicSubscriptionRepository) Delete(ctx context.Context, fcmToken, topic string) error {
+	query := `DELETE FROM topic_subscriptions WHERE fcm_token = $1 AND topic = $2`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, fcmToken, topic)
+	return err
+}
+
+// DeleteByToken menghapus semua langganan token yang sudah tidak valid
+func (r *TopicSubscriptionRepository) DeleteByToken(ctx context.Context, fcmToken string) error {
+	query := `DELETE FROM topic_subscriptions WHERE fcm_token = $1`
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, fcmToken)
+	return err
+}
+
+// FindUserIDsByTopics mengambil satu halaman pelanggan salah satu topics, urut user_id setelah
+// afterUserID. User yang berlangganan beberapa topic hanya muncul sekali.
+func (r *TopicSubscriptionRepository) FindUserIDsByTopics(ctx context.Context, topics []string, afterUserID uint, limit int) ([]uint, error) {
+	if len(topics) == 0 {
+		return nil, nil
+	}
+
+	query := `
+	SELECT DISTINCT user_id FROM topic_subscriptions
+	WHERE topic = ANY($1) AND user_id > $2
+	ORDER BY user_id
+	LIMIT $3
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, pq.Array(topics), afterUserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []uint
+	for rows.Next() {
+		var userID uint
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
@@ -2,11 +2,14 @@ package postgres
 
 import (
 	"backend/internal/entity"
+	"backend/pkg/bloodcompat"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type UserRepository struct {
@@ -21,8 +24,8 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
 	query := `
-	INSERT INTO users (email, password, role, name, date_of_birth, profile_photo, phone_number, gender, address, blood_type, rhesus, google_id, fcm_token,created_at, updated_at) 
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	INSERT INTO users (email, password, role, name, date_of_birth, profile_photo, phone_number, gender, address, blood_type, rhesus, google_id, created_at, updated_at) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 	RETURNING id
 	`
 
@@ -52,9 +55,8 @@ func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
 		user.BloodType,    // $10
 		user.Rhesus,       // $11
 		googleID,          // $12
-		user.FCMToken,     // $13
-		user.CreatedAt,    // $14
-		user.UpdatedAt,    // $15
+		user.CreatedAt,    // $13
+		user.UpdatedAt,    // $14
 	).Scan(&user.ID)
 
 	if err != nil {
@@ -69,7 +71,7 @@ func (r *UserRepository) FindById(ctx context.Context, id uint) (*entity.User, e
 	query := `
 	SELECT id, email, password, role, name, date_of_birth, profile_photo,
         phone_number, gender, address, blood_type, rhesus, google_id,
-		total_donation, coin, fcm_token, created_at, updated_at
+		total_donation, coin, latitude, longitude, created_at, updated_at
 	FROM users
 	WHERE id = $1
 	`
@@ -91,7 +93,8 @@ func (r *UserRepository) FindById(ctx context.Context, id uint) (*entity.User, e
 		&user.GoogleID,
 		&user.TotalDonation,
 		&user.Coin,
-		&user.FCMToken,
+		&user.Latitude,
+		&user.Longitude,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -112,7 +115,7 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*entity
     SELECT 
         id, email, password, role, name, date_of_birth, profile_photo,
         phone_number, gender, address, blood_type, rhesus, google_id,
-		total_donation, coin, fcm_token, created_at, updated_at
+		total_donation, coin, latitude, longitude, created_at, updated_at
     FROM users
     WHERE email = $1
     LIMIT 1
@@ -122,7 +125,6 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*entity
 	var (
 		profilePhoto sql.NullString
 		googleID     sql.NullString
-		fcmToken     sql.NullString
 	)
 
 	err := r.db.QueryRowContext(ctx, query, email).Scan(
@@ -141,7 +143,8 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*entity
 		&googleID,
 		&user.TotalDonation,
 		&user.Coin,
-		&fcmToken,
+		&user.Latitude,
+		&user.Longitude,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -157,9 +160,6 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*entity
 	if googleID.Valid {
 		user.GoogleID = &googleID.String
 	}
-	if fcmToken.Valid {
-		user.FCMToken = fcmToken.String
-	}
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -174,7 +174,8 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*entity
 // FindByGoogleID implements repository.UserRepository.
 func (r *UserRepository) FindByGoogleID(ctx context.Context, googleID string) (*entity.User, error) {
 	query := `
-	SELECT id, email, password, role, name, date_of_birth, profile_photo, phone_number, gender, address, blood_type, rhesus, google_id, created_at, updated_at
+	SELECT id, email, password, role, name, date_of_birth, profile_photo, phone_number, gender, address, blood_type, rhesus, google_id,
+		total_donation, coin, latitude, longitude, created_at, updated_at
 	FROM users
 	WHERE google_id = $1
 	`
@@ -196,6 +197,8 @@ func (r *UserRepository) FindByGoogleID(ctx context.Context, googleID string) (*
 		&user.GoogleID,
 		&user.TotalDonation,
 		&user.Coin,
+		&user.Latitude,
+		&user.Longitude,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -312,28 +315,22 @@ func (r *UserRepository) UpdateCoin(ctx context.Context, userID uint, coin int)
 	return err
 }
 
-func (r *UserRepository) FindFcmTokenByEmail(ctx context.Context, email string) (string, error) {
+// AddDonation menambah total donasi dan coin secara atomik, tanpa membaca nilai lama dulu
+func (r *UserRepository) AddDonation(ctx context.Context, userID uint, coin int) error {
 	query := `
-		SELECT fcm_token
-		FROM users
-		WHERE email = $1
+	UPDATE users SET total_donation = COALESCE(total_donation, 0) + 1, coin = COALESCE(coin, 0) + $1, updated_at = $2 WHERE id = $3
 	`
 
-	var fcmToken string
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&fcmToken)
-	return fcmToken, err
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, coin, time.Now(), userID)
+	return err
 }
 
-func (r *UserRepository) UpdateFcmTokenByEmail(ctx context.Context, email, fcmToken string) error {
+func (r *UserRepository) UpdateBloodType(ctx context.Context, userID uint, bloodType, rhesus string) error {
 	query := `
-		UPDATE users
-		SET fcm_token = $1, updated_at = $2
-		WHERE email = $3
+	UPDATE users SET blood_type = $1, rhesus = $2, updated_at = $3 WHERE id = $4
 	`
 
-	now := time.Now()
-
-	_, err := r.db.ExecContext(ctx, query, fcmToken, now, email)
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, bloodType, rhesus, time.Now(), userID)
 	return err
 }
 
@@ -348,3 +345,97 @@ func (r *UserRepository) GetCoinByUserID(ctx context.Context, userID uint) (int,
 	}
 	return coin, nil
 }
+
+func (r *UserRepository) UpdateLocation(ctx context.Context, userID uint, latitude, longitude float64) error {
+	query := `
+		UPDATE users
+		SET latitude = $1, longitude = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	now := time.Now()
+
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, latitude, longitude, now, userID)
+	return err
+}
+
+func (r *UserRepository) FindByRole(ctx context.Context, role string) ([]*entity.User, error) {
+	query := `
+	SELECT id, email, role, name
+	FROM users
+	WHERE role = $1
+	`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		user := &entity.User{}
+		if err := rows.Scan(&user.ID, &user.Email, &user.Role, &user.Name); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FindCompatibleDonorsNear mencari pendonor yang golongan darahnya termasuk `groups`,
+// sedang tidak dalam masa tunggu donor, dan berada dalam radius radiusKm dari titik
+// (latitude, longitude). Jarak dihitung dengan rumus haversine langsung di SQL.
+func (r *UserRepository) FindCompatibleDonorsNear(ctx context.Context, latitude, longitude, radiusKm float64, groups []bloodcompat.Group, limit int) ([]*entity.NearbyDonor, error) {
+	query := `
+	SELECT id, name, blood_type, rhesus, distance_km
+	FROM (
+		SELECT u.id, u.name, u.blood_type, u.rhesus,
+			` + haversineKm("u.latitude", "u.longitude") + ` AS distance_km
+		FROM users u
+		WHERE u.role = 'pendonor'
+			AND u.latitude IS NOT NULL AND u.longitude IS NOT NULL
+			AND (u.blood_type || ':' || u.rhesus) = ANY($3)
+			-- prefilter kotak kasar (1 derajat lintang ~ 111 km) agar tidak menghitung semua user
+			AND u.latitude BETWEEN $1 - ($4 / 111.0) AND $1 + ($4 / 111.0)
+			AND NOT EXISTS (
+				SELECT 1 FROM histories h
+				WHERE h.user_id = u.id AND h.next_donation > CURRENT_DATE
+			)
+	) d
+	WHERE distance_km <= $4
+	ORDER BY distance_km ASC
+	LIMIT $5
+	`
+
+	keys := make([]string, 0, len(groups))
+	for _, g := range groups {
+		keys = append(keys, g.BloodType+":"+g.Rhesus)
+	}
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, latitude, longitude, pq.Array(keys), radiusKm, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var donors []*entity.NearbyDonor
+	for rows.Next() {
+		d := &entity.NearbyDonor{}
+		if err := rows.Scan(&d.UserID, &d.Name, &d.BloodType, &d.Rhesus, &d.DistanceKm); err != nil {
+			return nil, err
+		}
+		donors = append(donors, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return donors, nil
+}
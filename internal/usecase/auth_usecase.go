@@ -7,25 +7,28 @@ import (
 	"backend/internal/repository"
 	"backend/pkg/hash"
 	"backend/pkg/jwt"
+	"backend/pkg/phone"
 	"context"
 	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"time"
-
-	firebase "firebase.google.com/go/v4"
-	"google.golang.org/api/option"
 )
 
+// webSocketTicketTTL adalah masa berlaku tiket websocket sebelum dipakai
+const webSocketTicketTTL = 30 * time.Second
+
 type authUseCase struct {
-	userRepo     repository.UserRepository
-	tokenRepo    repository.TokenRepository
-	fcmUseCase   FCMUseCase
-	jwtService   *jwt.JWTService
-	emailService *email.EmailService
-	googleOauth  *oauth.GooogleOauth
+	userRepo           repository.UserRepository
+	tokenRepo          repository.TokenRepository
+	fcmUseCase         FCMUseCase
+	deviceTokenUseCase DeviceTokenUseCase
+	jwtService         *jwt.JWTService
+	emailService       *email.EmailService
+	googleOauth        *oauth.GooogleOauth
 }
 
 func NewAuthUseCase(
@@ -33,16 +36,18 @@ func NewAuthUseCase(
 	tokenRepo repository.TokenRepository,
 	jwtService *jwt.JWTService,
 	fcmUseCase FCMUseCase,
+	deviceTokenUseCase DeviceTokenUseCase,
 	emailService *email.EmailService,
 	googleOauth *oauth.GooogleOauth,
 ) AuthUseCase {
 	return &authUseCase{
-		userRepo:     userRepo,
-		tokenRepo:    tokenRepo,
-		jwtService:   jwtService,
-		fcmUseCase:   fcmUseCase,
-		emailService: emailService,
-		googleOauth:  googleOauth,
+		userRepo:           userRepo,
+		tokenRepo:          tokenRepo,
+		jwtService:         jwtService,
+		fcmUseCase:         fcmUseCase,
+		deviceTokenUseCase: deviceTokenUseCase,
+		emailService:       emailService,
+		googleOauth:        googleOauth,
 	}
 }
 
@@ -71,6 +76,12 @@ func (a *authUseCase) Register(ctx context.Context, email, password, role, name
 		return nil, "", errors.New("user with this email already exists")
 	}
 
+	// nomor disimpan dalam format E.164 agar bisa dipakai gateway SMS / WhatsApp
+	phoneNumber, err = phone.NormalizeE164(phoneNumber, phone.DefaultCountryCode)
+	if err != nil {
+		return nil, "", ErrInvalidPhoneNumber
+	}
+
 	hashedPassword, err := hash.HashPassword(password)
 	if err != nil {
 		return nil, "", err
@@ -89,7 +100,6 @@ func (a *authUseCase) Register(ctx context.Context, email, password, role, name
 		BloodType:    bloodType,
 		Rhesus:       rhesus,
 		GoogleID:     nil,
-		FCMToken:     fcmToken,
 	}
 
 	err = a.userRepo.Create(ctx, user)
@@ -102,16 +112,11 @@ func (a *authUseCase) Register(ctx context.Context, email, password, role, name
 		return nil, "", err
 	}
 
-	opt := option.WithCredentialsFile("internal/infrastructure/broadcast/donora-f67f2-5c889d5acd0a.json")
-
-	app, err := firebase.NewApp(ctx, nil, opt)
-	if err != nil {
-		return nil, "", err
-	}
-
-	err = a.fcmUseCase.SubscribeUserToBloodTopic(ctx, app, fcmToken, bloodType)
-	if err != nil {
-		return nil, "", err
+	// gagal mendaftarkan perangkat tidak membatalkan registrasi, akan dicoba lagi saat login
+	if fcmToken != "" {
+		if _, err := a.deviceTokenUseCase.Register(ctx, user.ID, fcmToken, entity.DeviceUnknown, ""); err != nil {
+			log.Printf("Failed to register device for user %d: %v", user.ID, err)
+		}
 	}
 
 	return user, token, nil
@@ -393,22 +398,58 @@ func (a *authUseCase) Logout(ctx context.Context, userID uint, token string) err
 		CreatedAt: time.Now(),
 	}
 
-	return a.tokenRepo.Create(ctx, blacklistedToken)
+	if err := a.tokenRepo.Create(ctx, blacklistedToken); err != nil {
+		return err
+	}
+
+	// tiket websocket yang belum dipakai ikut dicabut
+	return a.tokenRepo.DeleteByUserID(ctx, userID, entity.WebSocketTicket)
+}
+
+// IssueWebSocketTicket implements AuthUseCase.
+// Tiket acak sekali pakai yang berlaku sebentar, dikirim sebagai query parameter saat upgrade
+// websocket sehingga JWT tidak ikut tercatat di URL atau header subprotocol.
+func (a *authUseCase) IssueWebSocketTicket(ctx context.Context, userID uint) (*entity.Token, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	// tiket yang tidak pernah dipakai tidak terhapus oleh Consume, jadi dibersihkan di sini
+	if err := a.tokenRepo.DeleteExpired(ctx, entity.WebSocketTicket, now); err != nil {
+		return nil, err
+	}
+
+	ticket := &entity.Token{
+		UserID:    userID,
+		Token:     hex.EncodeToString(b),
+		Type:      entity.WebSocketTicket,
+		ExpiresAt: now.Add(webSocketTicketTTL),
+		CreatedAt: now,
+	}
+
+	if err := a.tokenRepo.Create(ctx, ticket); err != nil {
+		return nil, err
+	}
+
+	return ticket, nil
 }
 
 // ValidateFcmToken implements AuthUseCase.
 func (a *authUseCase) ValidateFcmToken(ctx context.Context, userEmail, fcmToken string) error {
-	tokenDatabase, err := a.userRepo.FindFcmTokenByEmail(ctx, userEmail)
+	user, err := a.userRepo.FindByEmail(ctx, userEmail)
 	if err != nil {
 		return err
 	}
 
-	if tokenDatabase != fcmToken {
-		err := a.userRepo.UpdateFcmTokenByEmail(ctx, userEmail, fcmToken)
-		if err != nil {
-			return err
-		}
+	if user == nil {
+		return errors.New("user not found")
 	}
 
-	return nil
+	// perangkat yang login didaftarkan tanpa menghapus perangkat lain milik user;
+	// users.fcm_token tidak lagi ditulis, token hanya disimpan di device_tokens
+	_, err = a.deviceTokenUseCase.Register(ctx, user.ID, fcmToken, entity.DeviceUnknown, "")
+	return err
 }
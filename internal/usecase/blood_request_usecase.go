@@ -0,0 +1,510 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBloodRequestNotFound  = errors.New("blood request not found")
+	ErrBloodRequestForbidden = errors.New("blood request does not belong to this user")
+	ErrBloodRequestClosed    = errors.New("blood request is no longer available")
+	ErrInvalidBloodRequest   = errors.New("invalid blood request data")
+	ErrInvalidTransition     = errors.New("invalid blood request status transition")
+	ErrNotPencari            = errors.New("user role must be pencari")
+	ErrBloodTypeNotSet       = errors.New("user blood type and rhesus must be set")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+)
+
+// bloodRequestTransitions berisi transisi status yang diizinkan.
+// fulfilled, cancelled dan expired adalah status akhir.
+var bloodRequestTransitions = map[entity.BloodRequestStatus][]entity.BloodRequestStatus{
+	entity.BloodRequestOpen: {
+		entity.BloodRequestPartiallyPledged,
+		entity.BloodRequestFulfilled,
+		entity.BloodRequestCancelled,
+		entity.BloodRequestExpired,
+	},
+	entity.BloodRequestPartiallyPledged: {
+		entity.BloodRequestOpen,
+		entity.BloodRequestFulfilled,
+		entity.BloodRequestCancelled,
+		entity.BloodRequestExpired,
+	},
+}
+
+const (
+	DefaultDonorRadiusKm = 25.0
+	MaxDonorRadiusKm     = 200.0
+	maxNearbyDonors      = 100
+	defaultFeedLimit     = 20
+	maxFeedLimit         = 50
+)
+
+// defaultDeadlines dipakai jika pencari tidak memilih deadline sendiri
+var defaultDeadlines = map[int]time.Duration{
+	entity.UrgencyNormal:   7 * 24 * time.Hour,
+	entity.UrgencyUrgent:   3 * 24 * time.Hour,
+	entity.UrgencyCritical: 24 * time.Hour,
+}
+
+func canTransition(from, to entity.BloodRequestStatus) bool {
+	for _, allowed := range bloodRequestTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+type bloodRequestUseCase struct {
+	bloodRequestRepo repository.BloodRequestRepository
+	userRepo         repository.UserRepository
+	historyRepo      repository.HistoriesRepository
+	facilityRepo     repository.FacilityRepository
+}
+
+func NewBloodRequestUseCase(
+	bloodRequestRepo repository.BloodRequestRepository,
+	userRepo repository.UserRepository,
+	historyRepo repository.HistoriesRepository,
+	facilityRepo repository.FacilityRepository,
+) BloodRequestUseCase {
+	return &bloodRequestUseCase{
+		bloodRequestRepo: bloodRequestRepo,
+		userRepo:         userRepo,
+		historyRepo:      historyRepo,
+		facilityRepo:     facilityRepo,
+	}
+}
+
+func validateBloodRequest(searchName, location, bloodType, rhesus string, total, urgency int) error {
+	if searchName == "" || location == "" {
+		return ErrInvalidBloodRequest
+	}
+
+	if !bloodcompat.IsValid(bloodcompat.Group{BloodType: bloodType, Rhesus: rhesus}) {
+		return ErrInvalidBloodRequest
+	}
+
+	if total <= 0 || urgency < entity.UrgencyNormal || urgency > entity.UrgencyCritical {
+		return ErrInvalidBloodRequest
+	}
+
+	return nil
+}
+
+// validateCoordinates memastikan koordinat dikirim berpasangan dan dalam rentang yang benar
+func validateCoordinates(latitude, longitude *float64) error {
+	if latitude == nil && longitude == nil {
+		return nil
+	}
+
+	if latitude == nil || longitude == nil {
+		return ErrInvalidBloodRequest
+	}
+
+	if *latitude < -90 || *latitude > 90 || *longitude < -180 || *longitude > 180 {
+		return ErrInvalidBloodRequest
+	}
+
+	return nil
+}
+
+// resolveFacility memakai nama dan koordinat fasilitas jika pencari tidak mengisi lokasi sendiri
+func (b *bloodRequestUseCase) resolveFacility(ctx context.Context, facilityID *uint, location string, latitude, longitude *float64) (string, *float64, *float64, error) {
+	if facilityID == nil {
+		return location, latitude, longitude, nil
+	}
+
+	facility, err := b.facilityRepo.FindById(ctx, *facilityID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if facility == nil {
+		return "", nil, nil, ErrFacilityNotFound
+	}
+
+	if location == "" {
+		location = facility.Name
+	}
+
+	if latitude == nil && longitude == nil {
+		latitude, longitude = &facility.Latitude, &facility.Longitude
+	}
+
+	return location, latitude, longitude, nil
+}
+
+// resolveDeadline memakai deadline pilihan pencari atau default sesuai urgency
+func resolveDeadline(deadline *time.Time, urgency int, now time.Time) (*time.Time, error) {
+	if deadline == nil || deadline.IsZero() {
+		d := now.Add(defaultDeadlines[urgency])
+		return &d, nil
+	}
+
+	if !deadline.After(now) {
+		return nil, ErrInvalidBloodRequest
+	}
+
+	return deadline, nil
+}
+
+// findOwned mengambil blood request dan memastikan pemiliknya adalah userID
+func (b *bloodRequestUseCase) findOwned(ctx context.Context, userID, id uint) (*entity.BloodRequest, error) {
+	request, err := b.bloodRequestRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, ErrBloodRequestNotFound
+	}
+
+	if request.UserID != userID {
+		return nil, ErrBloodRequestForbidden
+	}
+
+	return request, nil
+}
+
+// Create implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) Create(ctx context.Context, userID uint, searchName, location string, facilityID *uint, latitude, longitude *float64, bloodType, rhesus string, total, urgency int, deadline *time.Time) (*entity.BloodRequest, error) {
+	location, latitude, longitude, err := b.resolveFacility(ctx, facilityID, location, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateBloodRequest(searchName, location, bloodType, rhesus, total, urgency); err != nil {
+		return nil, err
+	}
+
+	if err := validateCoordinates(latitude, longitude); err != nil {
+		return nil, err
+	}
+
+	deadline, err = resolveDeadline(deadline, urgency, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := b.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.Role != "pencari" {
+		return nil, ErrNotPencari
+	}
+
+	request := &entity.BloodRequest{
+		UserID:     userID,
+		SearchName: searchName,
+		Location:   location,
+		FacilityID: facilityID,
+		Latitude:   latitude,
+		Longitude:  longitude,
+		BloodType:  bloodType,
+		Rhesus:     rhesus,
+		Total:      total,
+		Urgency:    urgency,
+		Status:     entity.BloodRequestOpen,
+		Deadline:   deadline,
+	}
+
+	err = b.bloodRequestRepo.Create(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// GetByID implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) GetByID(ctx context.Context, id uint) (*entity.BloodRequest, error) {
+	request, err := b.bloodRequestRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, ErrBloodRequestNotFound
+	}
+
+	return request, nil
+}
+
+// GetByUserID implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) GetByUserID(ctx context.Context, userID uint) ([]*entity.BloodRequest, error) {
+	return b.bloodRequestRepo.FindByUserID(ctx, userID)
+}
+
+// Update implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) Update(ctx context.Context, userID, id uint, searchName, location string, facilityID *uint, latitude, longitude *float64, bloodType, rhesus string, total, urgency int, deadline *time.Time) (*entity.BloodRequest, error) {
+	location, latitude, longitude, err := b.resolveFacility(ctx, facilityID, location, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateBloodRequest(searchName, location, bloodType, rhesus, total, urgency); err != nil {
+		return nil, err
+	}
+
+	if err := validateCoordinates(latitude, longitude); err != nil {
+		return nil, err
+	}
+
+	request, err := b.findOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !request.Status.IsActive() {
+		return nil, ErrBloodRequestClosed
+	}
+
+	// deadline lama dipertahankan jika tidak dikirim ulang
+	if deadline != nil && !deadline.IsZero() {
+		if !deadline.After(time.Now()) {
+			return nil, ErrInvalidBloodRequest
+		}
+		request.Deadline = deadline
+	}
+
+	request.SearchName = searchName
+	request.Location = location
+	request.FacilityID = facilityID
+	if latitude != nil {
+		request.Latitude = latitude
+		request.Longitude = longitude
+	}
+	request.BloodType = bloodType
+	request.Rhesus = rhesus
+	request.Total = total
+	request.Urgency = urgency
+
+	err = b.bloodRequestRepo.Update(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// Cancel implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) Cancel(ctx context.Context, userID, id uint) error {
+	if _, err := b.findOwned(ctx, userID, id); err != nil {
+		return err
+	}
+
+	_, err := b.Transition(ctx, id, entity.BloodRequestCancelled)
+	return err
+}
+
+// Fulfill implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) Fulfill(ctx context.Context, userID, id uint) error {
+	if _, err := b.findOwned(ctx, userID, id); err != nil {
+		return err
+	}
+
+	_, err := b.Transition(ctx, id, entity.BloodRequestFulfilled)
+	return err
+}
+
+// FindNearbyDonors implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) FindNearbyDonors(ctx context.Context, userID, id uint, radiusKm float64) ([]*entity.NearbyDonor, error) {
+	request, err := b.findOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Latitude == nil || request.Longitude == nil {
+		return nil, fmt.Errorf("%w: blood request has no coordinates", ErrInvalidBloodRequest)
+	}
+
+	if radiusKm <= 0 {
+		radiusKm = DefaultDonorRadiusKm
+	}
+	if radiusKm > MaxDonorRadiusKm {
+		radiusKm = MaxDonorRadiusKm
+	}
+
+	groups := bloodcompat.DonorsFor(bloodcompat.Group{BloodType: request.BloodType, Rhesus: request.Rhesus})
+
+	return b.userRepo.FindCompatibleDonorsNear(ctx, *request.Latitude, *request.Longitude, radiusKm, groups, maxNearbyDonors)
+}
+
+// Transition implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) Transition(ctx context.Context, id uint, to entity.BloodRequestStatus) (*entity.BloodRequest, error) {
+	request, err := b.bloodRequestRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, ErrBloodRequestNotFound
+	}
+
+	if request.Status == to {
+		return request, nil
+	}
+
+	if !canTransition(request.Status, to) {
+		if !request.Status.IsActive() {
+			return nil, ErrBloodRequestClosed
+		}
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, request.Status, to)
+	}
+
+	updated, err := b.bloodRequestRepo.UpdateStatus(ctx, id, request.Status, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		// status sudah diubah proses lain di antara FindById dan UpdateStatus
+		return nil, fmt.Errorf("%w: status changed concurrently", ErrInvalidTransition)
+	}
+
+	return b.bloodRequestRepo.FindById(ctx, id)
+}
+
+// ExpireOverdue implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) ExpireOverdue(ctx context.Context) (int, error) {
+	ids, err := b.bloodRequestRepo.ExpireOverdue(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(ids), nil
+}
+
+// encodeFeedCursor menyimpan posisi item terakhir sebagai string opaque untuk client
+func encodeFeedCursor(item *entity.BloodRequestFeedItem) string {
+	raw := fmt.Sprintf("%d:%s:%d", item.Urgency, strconv.FormatFloat(item.SortDistance, 'g', -1, 64), item.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (*entity.FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCursor
+	}
+
+	urgency, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	distance, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &entity.FeedCursor{Urgency: urgency, DistanceKm: distance, ID: uint(id)}, nil
+}
+
+// Feed implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) Feed(ctx context.Context, userID uint, cursor string, limit int) (*entity.BloodRequestFeed, error) {
+	donor, err := b.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if donor == nil || donor.Role != "pendonor" {
+		return nil, ErrNotPendonor
+	}
+
+	group := bloodcompat.Group{BloodType: donor.BloodType, Rhesus: donor.Rhesus}
+	if !bloodcompat.IsValid(group) {
+		return nil, ErrBloodTypeNotSet
+	}
+
+	feed := &entity.BloodRequestFeed{Items: []*entity.BloodRequestFeedItem{}}
+
+	// pendonor yang masih dalam masa tunggu tidak bisa membantu request manapun
+	nextDonation, err := b.historyRepo.NextDonation(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if nextDonation.After(time.Now()) {
+		feed.NextDonation = &nextDonation
+		return feed, nil
+	}
+
+	var after *entity.FeedCursor
+	if cursor != "" {
+		after, err = decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limit <= 0 {
+		limit = defaultFeedLimit
+	}
+	if limit > maxFeedLimit {
+		limit = maxFeedLimit
+	}
+
+	// ambil satu item lebih untuk mengetahui apakah masih ada halaman berikutnya
+	items, err := b.bloodRequestRepo.FindFeed(ctx, userID, bloodcompat.RecipientsOf(group), donor.Latitude, donor.Longitude, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) > limit {
+		items = items[:limit]
+		feed.NextCursor = encodeFeedCursor(items[limit-1])
+	}
+
+	if items != nil {
+		feed.Items = items
+	}
+
+	return feed, nil
+}
+
+// RunExpirySweeper implements BloodRequestUseCase.
+func (b *bloodRequestUseCase) RunExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := b.ExpireOverdue(ctx)
+			if err != nil {
+				log.Printf("Failed to expire blood requests: %v", err)
+				continue
+			}
+
+			if expired > 0 {
+				log.Printf("Expired %d blood requests", expired)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"backend/configs"
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrBroadcastDisabled    = errors.New("broadcasts are temporarily disabled")
+	ErrBroadcastRateLimited = errors.New("broadcast limit reached, please try again later")
+	ErrDuplicateBroadcast   = errors.New("the same broadcast was sent recently")
+)
+
+type broadcastUseCase struct {
+	cfg              configs.BroadcastConfig
+	txManager        repository.TxManager
+	broadcastRepo    repository.BroadcastRepository
+	bloodRequestRepo repository.BloodRequestRepository
+	userRepo         repository.UserRepository
+	flagRepo         repository.FeatureFlagRepository
+	outboxRepo       repository.OutboxRepository
+}
+
+func NewBroadcastUseCase(
+	cfg configs.BroadcastConfig,
+	txManager repository.TxManager,
+	broadcastRepo repository.BroadcastRepository,
+	bloodRequestRepo repository.BloodRequestRepository,
+	userRepo repository.UserRepository,
+	flagRepo repository.FeatureFlagRepository,
+	outboxRepo repository.OutboxRepository,
+) BroadcastUseCase {
+	return &broadcastUseCase{
+		cfg:              cfg,
+		txManager:        txManager,
+		broadcastRepo:    broadcastRepo,
+		bloodRequestRepo: bloodRequestRepo,
+		userRepo:         userRepo,
+		flagRepo:         flagRepo,
+		outboxRepo:       outboxRepo,
+	}
+}
+
+// broadcastsDisabled membaca kill switch admin
+func broadcastsDisabled(ctx context.Context, flagRepo repository.FeatureFlagRepository) (bool, error) {
+	flag, err := flagRepo.FindByName(ctx, entity.FlagBroadcastsDisabled)
+	if err != nil {
+		return false, err
+	}
+	return flag.Enabled, nil
+}
+
+// broadcastText membuat teks notifikasi dari data blood request, bukan dari input pencari
+func broadcastText(request *entity.BloodRequest) (string, string) {
+	group := request.BloodType + rhesusSign(request.Rhesus)
+
+	title := fmt.Sprintf("Dibutuhkan darah %s", group)
+	if request.Urgency == entity.UrgencyCritical {
+		title = fmt.Sprintf("Darurat: dibutuhkan darah %s", group)
+	}
+
+	body := fmt.Sprintf("%s di %s membutuhkan %d kantong darah %s. Yuk bantu dengan menjadi pendonor!", request.SearchName, request.Location, request.Total, group)
+	return title, body
+}
+
+func broadcastHash(title, body string) string {
+	sum := sha256.Sum256([]byte(title + "\n" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Queue implements BroadcastUseCase.
+// User dan request dikunci selama pengecekan agar broadcast paralel, baik untuk request yang
+// sama maupun request lain milik user yang sama, tidak bisa melewati batas.
+func (b *broadcastUseCase) Queue(ctx context.Context, userID, bloodRequestID uint) (*entity.BloodBroadcast, error) {
+	if bloodRequestID == 0 {
+		return nil, fmt.Errorf("%w: blood_request_id is required", ErrInvalidBloodRequest)
+	}
+
+	disabled, err := broadcastsDisabled(ctx, b.flagRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	if disabled {
+		return nil, ErrBroadcastDisabled
+	}
+
+	user, err := b.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.Role != "pencari" {
+		return nil, ErrNotPencari
+	}
+
+	var broadcast *entity.BloodBroadcast
+	err = b.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := b.broadcastRepo.LockUser(ctx, userID); err != nil {
+			return err
+		}
+
+		request, err := b.bloodRequestRepo.FindByIdForUpdate(ctx, bloodRequestID)
+		if err != nil {
+			return err
+		}
+
+		if request == nil {
+			return ErrBloodRequestNotFound
+		}
+
+		if request.UserID != userID {
+			return ErrBloodRequestForbidden
+		}
+
+		if !request.Status.IsActive() {
+			return ErrBloodRequestClosed
+		}
+
+		title, body := broadcastText(request)
+		hash := broadcastHash(title, body)
+		now := time.Now()
+
+		duplicate, err := b.broadcastRepo.ExistsSince(ctx, request.ID, hash, now.Add(-b.cfg.DuplicateWindow))
+		if err != nil {
+			return err
+		}
+
+		if duplicate {
+			return ErrDuplicateBroadcast
+		}
+
+		requestCount, err := b.broadcastRepo.CountByRequestSince(ctx, request.ID, now.Add(-b.cfg.Window))
+		if err != nil {
+			return err
+		}
+
+		if requestCount >= b.cfg.PerRequestLimit {
+			return fmt.Errorf("%w: at most %d broadcasts per request every %s", ErrBroadcastRateLimited, b.cfg.PerRequestLimit, b.cfg.Window)
+		}
+
+		userCount, err := b.broadcastRepo.CountByUserSince(ctx, userID, now.Add(-b.cfg.Window))
+		if err != nil {
+			return err
+		}
+
+		if userCount >= b.cfg.PerUserLimit {
+			return fmt.Errorf("%w: at most %d broadcasts every %s", ErrBroadcastRateLimited, b.cfg.PerUserLimit, b.cfg.Window)
+		}
+
+		payload := entity.BroadcastPayload{
+			UserID:         userID,
+			BloodRequestID: request.ID,
+			BloodType:      request.BloodType,
+			Title:          title,
+			Body:           body,
+		}
+
+		message, err := enqueueOutbox(ctx, b.outboxRepo, entity.OutboxBroadcast, payload)
+		if err != nil {
+			return err
+		}
+
+		broadcast = &entity.BloodBroadcast{
+			UserID:         userID,
+			BloodRequestID: request.ID,
+			Title:          title,
+			Body:           body,
+			ContentHash:    hash,
+			OutboxID:       message.ID,
+		}
+
+		return b.broadcastRepo.Create(ctx, broadcast)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return broadcast, nil
+}
+
+// GetKillSwitch implements BroadcastUseCase.
+func (b *broadcastUseCase) GetKillSwitch(ctx context.Context, adminID uint) (*entity.FeatureFlag, error) {
+	if err := requireAdmin(ctx, b.userRepo, adminID); err != nil {
+		return nil, err
+	}
+
+	return b.flagRepo.FindByName(ctx, entity.FlagBroadcastsDisabled)
+}
+
+// SetKillSwitch implements BroadcastUseCase.
+// Saat aktif, broadcast baru ditolak dan broadcast yang masih di outbox tidak dikirim.
+func (b *broadcastUseCase) SetKillSwitch(ctx context.Context, adminID uint, disabled bool) (*entity.FeatureFlag, error) {
+	if err := requireAdmin(ctx, b.userRepo, adminID); err != nil {
+		return nil, err
+	}
+
+	flag := &entity.FeatureFlag{
+		Name:      entity.FlagBroadcastsDisabled,
+		Enabled:   disabled,
+		UpdatedBy: &adminID,
+	}
+
+	if err := b.flagRepo.Set(ctx, flag); err != nil {
+		return nil, err
+	}
+
+	return flag, nil
+}
@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"backend/configs"
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chatPreviewLength membatasi panjang cuplikan pesan di notifikasi
+const chatPreviewLength = 100
+
+// pendingChatPush menampung pesan untuk satu penerima yang sedang offline
+type pendingChatPush struct {
+	senders     []uint
+	count       int
+	lastPreview string
+	windowEnds  time.Time
+	timer       *time.Timer
+}
+
+type chatNotificationUseCase struct {
+	cfg        configs.ChatPushConfig
+	userRepo   repository.UserRepository
+	fcmUseCase FCMUseCase
+
+	mu      sync.Mutex
+	pending map[uint]*pendingChatPush
+}
+
+func NewChatNotificationUseCase(cfg configs.ChatPushConfig, userRepo repository.UserRepository, fcmUseCase FCMUseCase) ChatNotificationUseCase {
+	return &chatNotificationUseCase{
+		cfg:        cfg,
+		userRepo:   userRepo,
+		fcmUseCase: fcmUseCase,
+		pending:    make(map[uint]*pendingChatPush),
+	}
+}
+
+// chatCollapseKey dipakai untuk semua notifikasi chat satu penerima, sehingga notifikasi
+// berikutnya menggantikan notifikasi sebelumnya di perangkat
+func chatCollapseKey(receiverID uint) string {
+	return fmt.Sprintf("chat_%d", receiverID)
+}
+
+// chatPreview memotong isi pesan agar muat di notifikasi
+func chatPreview(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+
+	runes := []rune(content)
+	if len(runes) <= chatPreviewLength {
+		return content
+	}
+
+	return string(runes[:chatPreviewLength]) + "…"
+}
+
+// NotifyOffline implements ChatNotificationUseCase.
+// Push tidak langsung dikirim: pesan ditampung selama GracePeriod lalu dikirim sebagai satu
+// notifikasi. Pesan yang masuk selama CollapseWindow digabung dengan notifikasi sebelumnya.
+func (c *chatNotificationUseCase) NotifyOffline(ctx context.Context, senderID, receiverID uint, content string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	p, ok := c.pending[receiverID]
+	if !ok || (p.timer == nil && now.After(p.windowEnds)) {
+		p = &pendingChatPush{windowEnds: now.Add(c.cfg.CollapseWindow)}
+		c.pending[receiverID] = p
+	}
+
+	if !containsUint(p.senders, senderID) {
+		p.senders = append(p.senders, senderID)
+	}
+	p.count++
+	p.lastPreview = chatPreview(content)
+
+	if p.timer == nil {
+		p.timer = time.AfterFunc(c.cfg.GracePeriod, func() {
+			c.flush(receiverID, p)
+		})
+	}
+}
+
+// CancelPending implements ChatNotificationUseCase.
+// Dipanggil saat user tersambung lagi ke websocket; pesan akan diterima langsung lewat socket.
+func (c *chatNotificationUseCase) CancelPending(receiverID uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.pending[receiverID]
+	if !ok {
+		return
+	}
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	delete(c.pending, receiverID)
+}
+
+// flush mengirim notifikasi gabungan untuk p. p diabaikan jika sudah dibatalkan atau diganti.
+func (c *chatNotificationUseCase) flush(receiverID uint, p *pendingChatPush) {
+	c.mu.Lock()
+	if c.pending[receiverID] != p {
+		c.mu.Unlock()
+		return
+	}
+
+	p.timer = nil
+	senders := append([]uint(nil), p.senders...)
+	count := p.count
+	preview := p.lastPreview
+	remaining := time.Until(p.windowEnds)
+	c.mu.Unlock()
+
+	// hapus tampungan setelah jendela penggabungan lewat agar map tidak terus bertambah
+	time.AfterFunc(remaining, func() {
+		c.expire(receiverID, p)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	title, body := c.summarize(ctx, senders, count, preview)
+	data := map[string]string{
+		"type":          string(entity.NotificationChatMessage),
+		"sender_id":     strconv.FormatUint(uint64(senders[len(senders)-1]), 10),
+		"message_count": strconv.Itoa(count),
+	}
+
+	err := c.fcmUseCase.SendChatToUser(ctx, receiverID, title, body, chatCollapseKey(receiverID), data)
+	if err != nil && !errors.Is(err, ErrNoDeviceToken) && !errors.Is(err, ErrPushSuppressed) {
+		log.Printf("Failed to push chat notification to user %d: %v", receiverID, err)
+	}
+}
+
+// expire menghapus tampungan yang jendelanya sudah lewat dan tidak menunggu pengiriman
+func (c *chatNotificationUseCase) expire(receiverID uint, p *pendingChatPush) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending[receiverID] == p && p.timer == nil && time.Now().After(p.windowEnds) {
+		delete(c.pending, receiverID)
+	}
+}
+
+// summarize membuat judul dan isi notifikasi: nama pengirim dan cuplikan pesan, atau
+// jumlah pesan jika pesan berasal dari beberapa pengirim
+func (c *chatNotificationUseCase) summarize(ctx context.Context, senders []uint, count int, preview string) (string, string) {
+	names := make([]string, 0, len(senders))
+	for _, senderID := range senders {
+		names = append(names, c.senderName(ctx, senderID))
+	}
+
+	if len(names) == 1 {
+		if count == 1 {
+			return names[0], preview
+		}
+		return names[0], fmt.Sprintf("%d pesan baru: %s", count, preview)
+	}
+
+	title := fmt.Sprintf("%d pesan baru", count)
+	if len(names) > 3 {
+		return title, fmt.Sprintf("Dari %s dan %d lainnya", strings.Join(names[:3], ", "), len(names)-3)
+	}
+	return title, "Dari " + strings.Join(names, ", ")
+}
+
+func (c *chatNotificationUseCase) senderName(ctx context.Context, senderID uint) string {
+	user, err := c.userRepo.FindById(ctx, senderID)
+	if err != nil || user == nil || user.Name == "" {
+		return "Pengguna"
+	}
+
+	return user.Name
+}
+
+func containsUint(values []uint, value uint) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
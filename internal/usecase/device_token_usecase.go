@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"log"
+	"strings"
+)
+
+var (
+	ErrInvalidDeviceToken  = errors.New("invalid device token data")
+	ErrDeviceTokenNotFound = errors.New("device token not found")
+)
+
+type deviceTokenUseCase struct {
+	deviceTokenRepo     repository.DeviceTokenRepository
+	subscriptionUseCase TopicSubscriptionUseCase
+}
+
+func NewDeviceTokenUseCase(deviceTokenRepo repository.DeviceTokenRepository, subscriptionUseCase TopicSubscriptionUseCase) DeviceTokenUseCase {
+	return &deviceTokenUseCase{
+		deviceTokenRepo:     deviceTokenRepo,
+		subscriptionUseCase: subscriptionUseCase,
+	}
+}
+
+// Register implements DeviceTokenUseCase.
+func (d *deviceTokenUseCase) Register(ctx context.Context, userID uint, token string, platform entity.DevicePlatform, appVersion string) (*entity.DeviceToken, error) {
+	token = strings.TrimSpace(token)
+	if platform == "" {
+		platform = entity.DeviceUnknown
+	}
+
+	if token == "" || len(token) > 255 || !platform.IsValid() {
+		return nil, ErrInvalidDeviceToken
+	}
+
+	device := &entity.DeviceToken{
+		UserID:     userID,
+		Token:      token,
+		Platform:   platform,
+		AppVersion: strings.TrimSpace(appVersion),
+	}
+
+	previousUserID, err := d.deviceTokenRepo.Upsert(ctx, device)
+	if err != nil {
+		return nil, err
+	}
+
+	// token berpindah akun: topic pemilik lama dilepas sebelum topic pemilik baru dipasang
+	if previousUserID != 0 {
+		if err := d.subscriptionUseCase.Release(ctx, device.Token); err != nil {
+			log.Printf("Failed to release topic subscriptions of device %d from user %d: %v", device.ID, previousUserID, err)
+		}
+	}
+
+	// perangkat baru harus ikut topic broadcast; gagal sync akan dicoba lagi di perubahan berikutnya
+	if err := d.subscriptionUseCase.Sync(ctx, userID); err != nil {
+		log.Printf("Failed to sync topic subscriptions for user %d: %v", userID, err)
+	}
+
+	return device, nil
+}
+
+// Unregister implements DeviceTokenUseCase.
+func (d *deviceTokenUseCase) Unregister(ctx context.Context, userID uint, token string) error {
+	deleted, err := d.deviceTokenRepo.Delete(ctx, userID, strings.TrimSpace(token))
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return ErrDeviceTokenNotFound
+	}
+
+	// Sync meng-unsubscribe token yang sudah tidak terdaftar dari semua topic
+	if err := d.subscriptionUseCase.Sync(ctx, userID); err != nil {
+		log.Printf("Failed to sync topic subscriptions for user %d: %v", userID, err)
+	}
+
+	return nil
+}
+
+// GetByUserID implements DeviceTokenUseCase.
+func (d *deviceTokenUseCase) GetByUserID(ctx context.Context, userID uint) ([]*entity.DeviceToken, error) {
+	return d.deviceTokenRepo.FindByUserID(ctx, userID)
+}
@@ -0,0 +1,247 @@
+package usecase
+
+import (
+	"backend/configs"
+	"backend/internal/entity"
+	"backend/internal/infrastructure/email"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	// reminderSuggestionLimit membatasi jumlah request / fasilitas terdekat di pengingat
+	reminderSuggestionLimit = 3
+	// reminderMaxAttempts membatasi percobaan pengingat yang belum sampai lewat channel mana pun
+	reminderMaxAttempts = 5
+	// reminderRetryDelay adalah jeda sebelum pengingat yang gagal atau terhenti di tengah jalan dicoba lagi
+	reminderRetryDelay = time.Hour
+)
+
+type donationReminderUseCase struct {
+	cfg               configs.ReminderConfig
+	reminderRepo      repository.DonationReminderRepository
+	userRepo          repository.UserRepository
+	bloodRequestRepo  repository.BloodRequestRepository
+	facilityRepo      repository.FacilityRepository
+	preferenceUseCase NotificationPreferenceUseCase
+	fcmUseCase        FCMUseCase
+	emailService      *email.EmailService
+}
+
+func NewDonationReminderUseCase(cfg configs.ReminderConfig, reminderRepo repository.DonationReminderRepository, userRepo repository.UserRepository, bloodRequestRepo repository.BloodRequestRepository, facilityRepo repository.FacilityRepository, preferenceUseCase NotificationPreferenceUseCase, fcmUseCase FCMUseCase, emailService *email.EmailService) DonationReminderUseCase {
+	return &donationReminderUseCase{
+		cfg:               cfg,
+		reminderRepo:      reminderRepo,
+		userRepo:          userRepo,
+		bloodRequestRepo:  bloodRequestRepo,
+		facilityRepo:      facilityRepo,
+		preferenceUseCase: preferenceUseCase,
+		fcmUseCase:        fcmUseCase,
+		emailService:      emailService,
+	}
+}
+
+// reminderSuggestions berisi request terbuka atau fasilitas terdekat yang disertakan di pengingat
+type reminderSuggestions struct {
+	lines []string
+	data  map[string]string
+}
+
+// SendDue implements DonationReminderUseCase.
+func (d *donationReminderUseCase) SendDue(ctx context.Context) (int, error) {
+	now := time.Now()
+
+	// next_donation disimpan sebagai DATE, "hari ini" dihitung di zona waktu default aplikasi
+	loc, err := time.LoadLocation(entity.DefaultTimezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	due, err := d.reminderRepo.FindDue(ctx, today, today.AddDate(0, 0, d.cfg.DaysBefore), now, reminderMaxAttempts, d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, reminder := range due {
+		preference, err := d.preferenceUseCase.Get(ctx, reminder.UserID)
+		if err != nil {
+			log.Printf("Failed to get notification preference of user %d: %v", reminder.UserID, err)
+			continue
+		}
+
+		// tunda sampai quiet hours selesai agar tidak diambil lagi di setiap siklus sebelum itu
+		if preference.Allows(entity.CategoryReminder) && preference.InQuietHours(now) {
+			if err := d.reminderRepo.Defer(ctx, reminder, preference.QuietHoursUntil(now), now); err != nil {
+				log.Printf("Failed to defer donation reminder for user %d: %v", reminder.UserID, err)
+			}
+			continue
+		}
+
+		// catat terlebih dahulu agar restart atau instance lain tidak mengirim ulang
+		claimed, err := d.reminderRepo.Claim(ctx, reminder, now, now.Add(reminderRetryDelay))
+		if err != nil {
+			log.Printf("Failed to claim donation reminder for user %d: %v", reminder.UserID, err)
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		if !preference.Allows(entity.CategoryReminder) {
+			if err := d.reminderRepo.MarkSent(ctx, reminder.ID, false, false, nil); err != nil {
+				log.Printf("Failed to record donation reminder %d: %v", reminder.ID, err)
+			}
+			continue
+		}
+
+		pushSent, emailSent := d.remind(ctx, reminder, today)
+
+		// dicoba lagi sampai minimal satu channel berhasil atau percobaan habis
+		var nextAttemptAt *time.Time
+		if !pushSent && !emailSent && reminder.Attempts < reminderMaxAttempts {
+			retryAt := time.Now().Add(reminderRetryDelay)
+			nextAttemptAt = &retryAt
+		}
+
+		if err := d.reminderRepo.MarkSent(ctx, reminder.ID, pushSent, emailSent, nextAttemptAt); err != nil {
+			log.Printf("Failed to record donation reminder %d: %v", reminder.ID, err)
+		}
+
+		if pushSent || emailSent {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// remind mengirim pengingat lewat push dan email. today adalah tanggal hari ini di zona waktu
+// default aplikasi, disimpan dalam UTC seperti EligibleOn.
+func (d *donationReminderUseCase) remind(ctx context.Context, reminder *entity.DonationReminder, today time.Time) (bool, bool) {
+	user, err := d.userRepo.FindById(ctx, reminder.UserID)
+	if err != nil || user == nil {
+		log.Printf("Failed to find user %d for donation reminder: %v", reminder.UserID, err)
+		return false, false
+	}
+
+	suggestions := d.suggestions(ctx, user)
+
+	title := "Kamu sudah bisa donor darah lagi"
+	body := "Masa tunggu donormu sudah selesai. Yuk bantu yang membutuhkan!"
+	if reminder.EligibleOn.After(today) {
+		title = "Sebentar lagi kamu bisa donor darah lagi"
+		body = fmt.Sprintf("Kamu bisa donor lagi mulai %s.", reminder.EligibleOn.Format("02-01-2006"))
+	}
+	if len(suggestions.lines) > 0 {
+		body += " " + suggestions.lines[0]
+	}
+
+	data := map[string]string{
+		"type":        string(entity.NotificationDonationReminder),
+		"eligible_on": reminder.EligibleOn.Format("2006-01-02"),
+	}
+	for key, value := range suggestions.data {
+		data[key] = value
+	}
+
+	pushSent := true
+	if err := d.fcmUseCase.SendToUser(ctx, user.ID, title, body, data); err != nil {
+		pushSent = false
+		if !errors.Is(err, ErrNoDeviceToken) && !errors.Is(err, ErrPushSuppressed) {
+			log.Printf("Failed to push donation reminder to user %d: %v", user.ID, err)
+		}
+	}
+
+	emailSent := false
+	if user.Email != "" {
+		if err := d.emailService.SendDonationReminderEmail(user.Email, user.Name, reminder.EligibleOn, suggestions.lines); err != nil {
+			log.Printf("Failed to email donation reminder to user %d: %v", user.ID, err)
+		} else {
+			emailSent = true
+		}
+	}
+
+	return pushSent, emailSent
+}
+
+// suggestions mencari request terbuka yang cocok dengan golongan darah pendonor, atau
+// fasilitas donor terdekat jika tidak ada request
+func (d *donationReminderUseCase) suggestions(ctx context.Context, user *entity.User) reminderSuggestions {
+	result := reminderSuggestions{data: map[string]string{}}
+
+	donor := bloodcompat.Group{BloodType: user.BloodType, Rhesus: user.Rhesus}
+	if bloodcompat.IsValid(donor) {
+		items, err := d.bloodRequestRepo.FindFeed(ctx, user.ID, bloodcompat.RecipientsOf(donor), user.Latitude, user.Longitude, nil, reminderSuggestionLimit)
+		if err != nil {
+			log.Printf("Failed to find blood requests for donation reminder of user %d: %v", user.ID, err)
+		}
+
+		for _, item := range items {
+			// request yang terlalu jauh tidak disarankan; request tanpa koordinat tetap disertakan
+			if item.DistanceKm != nil && *item.DistanceKm > d.cfg.RadiusKm {
+				continue
+			}
+
+			result.lines = append(result.lines, fmt.Sprintf("%s di %s membutuhkan darah %s%s.", item.SearchName, item.Location, item.BloodType, rhesusSign(item.Rhesus)))
+			if _, ok := result.data["blood_request_id"]; !ok {
+				result.data["blood_request_id"] = strconv.FormatUint(uint64(item.ID), 10)
+			}
+		}
+
+		if len(result.lines) > 0 {
+			return result
+		}
+	}
+
+	if user.Latitude == nil || user.Longitude == nil {
+		return result
+	}
+
+	facilities, err := d.facilityRepo.FindNearest(ctx, *user.Latitude, *user.Longitude, d.cfg.RadiusKm, reminderSuggestionLimit)
+	if err != nil {
+		log.Printf("Failed to find facilities for donation reminder of user %d: %v", user.ID, err)
+		return result
+	}
+
+	for _, facility := range facilities {
+		result.lines = append(result.lines, fmt.Sprintf("Donor di %s (%.1f km).", facility.Name, facility.DistanceKm))
+		if _, ok := result.data["facility_id"]; !ok {
+			result.data["facility_id"] = strconv.FormatUint(uint64(facility.ID), 10)
+		}
+	}
+
+	return result
+}
+
+// RunReminderScheduler implements DonationReminderUseCase.
+func (d *donationReminderUseCase) RunReminderScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sent, err := d.SendDue(ctx)
+			if err != nil {
+				log.Printf("Failed to send donation reminders: %v", err)
+				continue
+			}
+
+			if sent > 0 {
+				log.Printf("Sent %d donation reminders", sent)
+			}
+		}
+	}
+}
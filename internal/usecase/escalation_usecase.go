@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"backend/configs"
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// maxEscalationLevel adalah level terakhir: wider_radius -> all_compatible_groups -> admins
+const maxEscalationLevel = 3
+
+// ErrEscalationNotFound berarti eskalasi yang dirujuk outbox sudah tidak ada
+var ErrEscalationNotFound = errors.New("escalation not found")
+
+type escalationUseCase struct {
+	cfg              configs.EscalationConfig
+	txManager        repository.TxManager
+	bloodRequestRepo repository.BloodRequestRepository
+	outboxRepo       repository.OutboxRepository
+	fcmUseCase       FCMUseCase
+}
+
+func NewEscalationUseCase(cfg configs.EscalationConfig, txManager repository.TxManager, bloodRequestRepo repository.BloodRequestRepository, outboxRepo repository.OutboxRepository, fcmUseCase FCMUseCase) EscalationUseCase {
+	return &escalationUseCase{
+		cfg:              cfg,
+		txManager:        txManager,
+		bloodRequestRepo: bloodRequestRepo,
+		outboxRepo:       outboxRepo,
+		fcmUseCase:       fcmUseCase,
+	}
+}
+
+// escalationActionFor memetakan level eskalasi ke aksi yang dijalankan
+func escalationActionFor(level int) entity.EscalationAction {
+	switch level {
+	case 1:
+		return entity.EscalationWiderRadius
+	case 2:
+		return entity.EscalationAllCompatibleGroups
+	default:
+		return entity.EscalationAdmins
+	}
+}
+
+// isEscalationDue menghitung jeda dari eskalasi terakhir, atau dari pembuatan request
+func (e *escalationUseCase) isEscalationDue(request *entity.BloodRequest, now time.Time) bool {
+	since := request.CreatedAt
+	if request.EscalatedAt != nil {
+		since = *request.EscalatedAt
+	}
+
+	return now.Sub(since) >= e.windowFor(request.Urgency)
+}
+
+// windowFor mengembalikan jeda eskalasi sesuai tingkat urgency
+func (e *escalationUseCase) windowFor(urgency int) time.Duration {
+	switch urgency {
+	case entity.UrgencyCritical:
+		return e.cfg.CriticalWindow
+	case entity.UrgencyUrgent:
+		return e.cfg.UrgentWindow
+	default:
+		return e.cfg.NormalWindow
+	}
+}
+
+// errEscalationTaken menandakan level sudah dinaikkan instance lain
+var errEscalationTaken = errors.New("escalation level already claimed")
+
+// nextEscalationLevel melewati wider_radius untuk request tanpa koordinat karena radius
+// tidak bisa diperluas; request langsung dikirim ke semua golongan darah yang cocok
+func nextEscalationLevel(request *entity.BloodRequest) int {
+	level := request.EscalationLevel + 1
+	if escalationActionFor(level) == entity.EscalationWiderRadius && (request.Latitude == nil || request.Longitude == nil) {
+		level++
+	}
+	return level
+}
+
+// EscalateDue implements EscalationUseCase.
+func (e *escalationUseCase) EscalateDue(ctx context.Context) (int, error) {
+	requests, err := e.bloodRequestRepo.FindEscalationCandidates(ctx, maxEscalationLevel)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	escalated := 0
+	for _, request := range requests {
+		if !e.isEscalationDue(request, now) {
+			continue
+		}
+
+		from := request.EscalationLevel
+		level := nextEscalationLevel(request)
+
+		// level dinaikkan di transaksi yang sama dengan pencatatan eskalasi dan antrean notifikasinya.
+		// Notifikasi dikirim worker outbox setelah commit, sehingga instance lain tidak mengirim
+		// ulang dan kegagalan pengiriman dicoba lagi oleh outbox tanpa menaikkan level dua kali.
+		err := e.txManager.WithinTx(ctx, func(txCtx context.Context) error {
+			claimed, err := e.bloodRequestRepo.ClaimEscalation(txCtx, request.ID, from, level, now)
+			if err != nil {
+				return err
+			}
+
+			if !claimed {
+				return errEscalationTaken
+			}
+
+			escalation := &entity.BloodRequestEscalation{
+				BloodRequestID: request.ID,
+				Level:          level,
+				Action:         escalationActionFor(level),
+			}
+
+			if err := e.bloodRequestRepo.CreateEscalation(txCtx, escalation); err != nil {
+				return err
+			}
+
+			_, err = enqueueOutbox(txCtx, e.outboxRepo, entity.OutboxEscalation, entity.EscalationPayload{EscalationID: escalation.ID})
+			return err
+		})
+
+		if errors.Is(err, errEscalationTaken) {
+			continue
+		}
+
+		if err != nil {
+			log.Printf("Failed to escalate blood request %d to level %d: %v", request.ID, level, err)
+			continue
+		}
+
+		escalated++
+	}
+
+	return escalated, nil
+}
+
+// DeliverEscalation implements EscalationUseCase.
+// Dipanggil worker outbox untuk mengirim notifikasi eskalasi yang sudah dicatat EscalateDue.
+// Request yang sudah ditutup sebelum notifikasinya terkirim dilewati.
+func (e *escalationUseCase) DeliverEscalation(ctx context.Context, escalationID uint) error {
+	escalation, err := e.bloodRequestRepo.FindEscalationById(ctx, escalationID)
+	if err != nil {
+		return err
+	}
+
+	if escalation == nil {
+		return ErrEscalationNotFound
+	}
+
+	request, err := e.bloodRequestRepo.FindById(ctx, escalation.BloodRequestID)
+	if err != nil {
+		return err
+	}
+
+	if request == nil || !request.Status.IsActive() {
+		return nil
+	}
+
+	notified, err := e.escalate(ctx, request, escalation.Level)
+	if err != nil {
+		return err
+	}
+
+	return e.bloodRequestRepo.UpdateEscalationNotified(ctx, escalation.ID, notified)
+}
+
+func (e *escalationUseCase) escalate(ctx context.Context, request *entity.BloodRequest, level int) (int, error) {
+	title := fmt.Sprintf("Dibutuhkan segera: darah %s%s", request.BloodType, rhesusSign(request.Rhesus))
+	body := fmt.Sprintf("%s di %s masih membutuhkan %d kantong darah", request.SearchName, request.Location, request.Total)
+
+	var (
+		notified int
+		err      error
+	)
+
+	switch escalationActionFor(level) {
+	case entity.EscalationWiderRadius:
+		notified, err = e.fcmUseCase.NotifyNearbyDonors(ctx, request, e.cfg.WideRadiusKm, title, body)
+	case entity.EscalationAllCompatibleGroups:
+		notified, err = e.fcmUseCase.NotifyCompatibleGroups(ctx, request, title, body)
+	default:
+		adminTitle := fmt.Sprintf("Eskalasi blood request #%d", request.ID)
+		notified, err = e.fcmUseCase.NotifyAdmins(ctx, request, adminTitle, body)
+	}
+
+	// tidak ada penerima yang punya perangkat bukan kegagalan; eskalasi tetap lanjut ke level berikutnya
+	if errors.Is(err, ErrNoDeviceToken) {
+		return notified, nil
+	}
+
+	return notified, err
+}
+
+func rhesusSign(rhesus string) string {
+	if rhesus == bloodcompat.Negative {
+		return "-"
+	}
+	return "+"
+}
+
+// GetByBloodRequestID implements EscalationUseCase.
+func (e *escalationUseCase) GetByBloodRequestID(ctx context.Context, userID, bloodRequestID uint) ([]*entity.BloodRequestEscalation, error) {
+	request, err := e.bloodRequestRepo.FindById(ctx, bloodRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, ErrBloodRequestNotFound
+	}
+
+	if request.UserID != userID {
+		return nil, ErrBloodRequestForbidden
+	}
+
+	return e.bloodRequestRepo.FindEscalations(ctx, bloodRequestID)
+}
+
+// RunEscalationScheduler implements EscalationUseCase.
+func (e *escalationUseCase) RunEscalationScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			escalated, err := e.EscalateDue(ctx)
+			if err != nil {
+				log.Printf("Failed to escalate blood requests: %v", err)
+				continue
+			}
+
+			if escalated > 0 {
+				log.Printf("Escalated %d blood requests", escalated)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"backend/configs"
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrNotFacilityPartner = errors.New("user is not a partner of this facility")
+
+const (
+	defaultStockHistoryLimit = 50
+	maxStockHistoryLimit     = 500
+)
+
+type facilityStockUseCase struct {
+	cfg          configs.StockConfig
+	txManager    repository.TxManager
+	facilityRepo repository.FacilityRepository
+	stockRepo    repository.FacilityStockRepository
+	userRepo     repository.UserRepository
+	outboxRepo   repository.OutboxRepository
+}
+
+func NewFacilityStockUseCase(
+	cfg configs.StockConfig,
+	txManager repository.TxManager,
+	facilityRepo repository.FacilityRepository,
+	stockRepo repository.FacilityStockRepository,
+	userRepo repository.UserRepository,
+	outboxRepo repository.OutboxRepository,
+) FacilityStockUseCase {
+	return &facilityStockUseCase{
+		cfg:          cfg,
+		txManager:    txManager,
+		facilityRepo: facilityRepo,
+		stockRepo:    stockRepo,
+		userRepo:     userRepo,
+		outboxRepo:   outboxRepo,
+	}
+}
+
+// requirePartner mengizinkan admin atau petugas yang terdaftar di fasilitas tersebut
+func (s *facilityStockUseCase) requirePartner(ctx context.Context, userID, facilityID uint) error {
+	if err := requireAdmin(ctx, s.userRepo, userID); err == nil {
+		return nil
+	} else if !errors.Is(err, ErrNotAdmin) {
+		return err
+	}
+
+	isPartner, err := s.facilityRepo.IsPartner(ctx, facilityID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !isPartner {
+		return ErrNotFacilityPartner
+	}
+
+	return nil
+}
+
+// ReportStock implements FacilityStockUseCase.
+func (s *facilityStockUseCase) ReportStock(ctx context.Context, userID, facilityID uint, levels []entity.StockLevel) ([]*entity.FacilityStock, error) {
+	if len(levels) == 0 {
+		return nil, ErrInvalidFacility
+	}
+
+	for _, level := range levels {
+		if !bloodcompat.IsValid(bloodcompat.Group{BloodType: level.BloodType, Rhesus: level.Rhesus}) || level.Units < 0 {
+			return nil, ErrInvalidFacility
+		}
+	}
+
+	facility, err := s.facilityRepo.FindById(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if facility == nil {
+		return nil, ErrFacilityNotFound
+	}
+
+	if err := s.requirePartner(ctx, userID, facilityID); err != nil {
+		return nil, err
+	}
+
+	err = s.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		for _, level := range levels {
+			stock := &entity.FacilityStock{
+				FacilityID: facilityID,
+				BloodType:  level.BloodType,
+				Rhesus:     level.Rhesus,
+				Units:      level.Units,
+			}
+
+			if err := s.stockRepo.UpsertStock(ctx, stock); err != nil {
+				return err
+			}
+
+			history := &entity.FacilityStockHistory{
+				FacilityID: facilityID,
+				BloodType:  level.BloodType,
+				Rhesus:     level.Rhesus,
+				Units:      level.Units,
+				ReportedBy: &userID,
+			}
+
+			if err := s.stockRepo.CreateHistory(ctx, history); err != nil {
+				return err
+			}
+
+			if level.Units >= s.cfg.ShortageThreshold {
+				if err := s.stockRepo.ResolveShortage(ctx, facilityID, level.BloodType, level.Rhesus, now); err != nil {
+					return err
+				}
+				continue
+			}
+
+			shortage := &entity.FacilityShortage{
+				FacilityID: facilityID,
+				BloodType:  level.BloodType,
+				Rhesus:     level.Rhesus,
+				Units:      level.Units,
+			}
+
+			created, err := s.stockRepo.CreateShortage(ctx, shortage)
+			if err != nil {
+				return err
+			}
+
+			if !created {
+				continue
+			}
+
+			// notifikasi dikirim outbox worker agar laporan stok tidak tertahan oleh FCM
+			if err := s.notifyShortage(ctx, facility, shortage); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.stockRepo.FindByFacilityID(ctx, facilityID)
+}
+
+// notifyShortage hanya menghubungi pendonor dengan golongan darah yang sama persis,
+// karena stok fasilitas disimpan per golongan darah kantong, bukan per penerima
+func (s *facilityStockUseCase) notifyShortage(ctx context.Context, facility *entity.Facility, shortage *entity.FacilityShortage) error {
+	group := bloodcompat.Group{BloodType: shortage.BloodType, Rhesus: shortage.Rhesus}
+
+	title := fmt.Sprintf("Stok darah %s%s menipis", shortage.BloodType, rhesusSign(shortage.Rhesus))
+	body := fmt.Sprintf("%s membutuhkan pendonor %s%s. Yuk donor darah hari ini!", facility.Name, shortage.BloodType, rhesusSign(shortage.Rhesus))
+	data := map[string]string{
+		"type":        string(entity.NotificationStockShortage),
+		"facility_id": strconv.FormatUint(uint64(facility.ID), 10),
+		"blood_type":  shortage.BloodType,
+		"rhesus":      shortage.Rhesus,
+	}
+
+	payload := entity.DonorsNearPayload{
+		ShortageID: shortage.ID,
+		Latitude:   facility.Latitude,
+		Longitude:  facility.Longitude,
+		RadiusKm:   s.cfg.AlertRadiusKm,
+		Groups:     []bloodcompat.Group{group},
+		Title:      title,
+		Body:       body,
+		Data:       data,
+	}
+
+	_, err := enqueueOutbox(ctx, s.outboxRepo, entity.OutboxDonorsNear, payload)
+	return err
+}
+
+// GetStocks implements FacilityStockUseCase.
+func (s *facilityStockUseCase) GetStocks(ctx context.Context, facilityID uint) ([]*entity.FacilityStock, error) {
+	facility, err := s.facilityRepo.FindById(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if facility == nil {
+		return nil, ErrFacilityNotFound
+	}
+
+	return s.stockRepo.FindByFacilityID(ctx, facilityID)
+}
+
+// GetHistory implements FacilityStockUseCase.
+func (s *facilityStockUseCase) GetHistory(ctx context.Context, facilityID uint, limit int) ([]*entity.FacilityStockHistory, error) {
+	facility, err := s.facilityRepo.FindById(ctx, facilityID)
+	if err != nil {
+		return nil, err
+	}
+
+	if facility == nil {
+		return nil, ErrFacilityNotFound
+	}
+
+	if limit <= 0 {
+		limit = defaultStockHistoryLimit
+	}
+	if limit > maxStockHistoryLimit {
+		limit = maxStockHistoryLimit
+	}
+
+	return s.stockRepo.FindHistory(ctx, facilityID, limit)
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrFacilityNotFound = errors.New("facility not found")
+	ErrInvalidFacility  = errors.New("invalid facility data")
+	ErrNotAdmin         = errors.New("user role must be admin")
+)
+
+const (
+	DefaultFacilityRadiusKm = 50.0
+	defaultFacilityLimit    = 10
+	maxFacilityLimit        = 50
+)
+
+type facilityUseCase struct {
+	facilityRepo repository.FacilityRepository
+	userRepo     repository.UserRepository
+}
+
+func NewFacilityUseCase(facilityRepo repository.FacilityRepository, userRepo repository.UserRepository) FacilityUseCase {
+	return &facilityUseCase{
+		facilityRepo: facilityRepo,
+		userRepo:     userRepo,
+	}
+}
+
+// requireAdmin memastikan hanya admin yang bisa mengelola data fasilitas
+func requireAdmin(ctx context.Context, userRepo repository.UserRepository, userID uint) error {
+	user, err := userRepo.FindById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil || user.Role != "admin" {
+		return ErrNotAdmin
+	}
+
+	return nil
+}
+
+func validateFacility(facility *entity.Facility) error {
+	facility.Name = strings.TrimSpace(facility.Name)
+	facility.Address = strings.TrimSpace(facility.Address)
+
+	if facility.Name == "" || facility.Address == "" || !facility.Type.IsValid() {
+		return ErrInvalidFacility
+	}
+
+	if err := validateCoordinates(&facility.Latitude, &facility.Longitude); err != nil {
+		return ErrInvalidFacility
+	}
+
+	for _, bloodType := range facility.BloodTypes {
+		if !bloodcompat.IsValidBloodType(bloodType) {
+			return ErrInvalidFacility
+		}
+	}
+
+	if facility.BloodTypes == nil {
+		facility.BloodTypes = []string{}
+	}
+
+	return nil
+}
+
+// Create implements FacilityUseCase.
+func (f *facilityUseCase) Create(ctx context.Context, userID uint, facility *entity.Facility) error {
+	if err := requireAdmin(ctx, f.userRepo, userID); err != nil {
+		return err
+	}
+
+	if err := validateFacility(facility); err != nil {
+		return err
+	}
+
+	return f.facilityRepo.Create(ctx, facility)
+}
+
+// GetByID implements FacilityUseCase.
+func (f *facilityUseCase) GetByID(ctx context.Context, id uint) (*entity.Facility, error) {
+	facility, err := f.facilityRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if facility == nil {
+		return nil, ErrFacilityNotFound
+	}
+
+	return facility, nil
+}
+
+// GetAll implements FacilityUseCase.
+func (f *facilityUseCase) GetAll(ctx context.Context, facilityType entity.FacilityType) ([]*entity.Facility, error) {
+	if facilityType != "" && !facilityType.IsValid() {
+		return nil, ErrInvalidFacility
+	}
+
+	return f.facilityRepo.FindAll(ctx, facilityType)
+}
+
+// Update implements FacilityUseCase.
+func (f *facilityUseCase) Update(ctx context.Context, userID uint, facility *entity.Facility) error {
+	if err := requireAdmin(ctx, f.userRepo, userID); err != nil {
+		return err
+	}
+
+	existing, err := f.GetByID(ctx, facility.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := validateFacility(facility); err != nil {
+		return err
+	}
+
+	facility.CreatedAt = existing.CreatedAt
+	return f.facilityRepo.Update(ctx, facility)
+}
+
+// Delete implements FacilityUseCase.
+func (f *facilityUseCase) Delete(ctx context.Context, userID, id uint) error {
+	if err := requireAdmin(ctx, f.userRepo, userID); err != nil {
+		return err
+	}
+
+	if _, err := f.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	return f.facilityRepo.Delete(ctx, id)
+}
+
+// FindNearest implements FacilityUseCase.
+func (f *facilityUseCase) FindNearest(ctx context.Context, latitude, longitude, radiusKm float64, limit int) ([]*entity.NearbyFacility, error) {
+	if err := validateCoordinates(&latitude, &longitude); err != nil {
+		return nil, ErrInvalidFacility
+	}
+
+	if radiusKm <= 0 {
+		radiusKm = DefaultFacilityRadiusKm
+	}
+	if radiusKm > MaxDonorRadiusKm {
+		radiusKm = MaxDonorRadiusKm
+	}
+
+	if limit <= 0 {
+		limit = defaultFacilityLimit
+	}
+	if limit > maxFacilityLimit {
+		limit = maxFacilityLimit
+	}
+
+	return f.facilityRepo.FindNearest(ctx, latitude, longitude, radiusKm, limit)
+}
+
+// AddPartner implements FacilityUseCase.
+func (f *facilityUseCase) AddPartner(ctx context.Context, adminID, facilityID, userID uint) error {
+	if err := requireAdmin(ctx, f.userRepo, adminID); err != nil {
+		return err
+	}
+
+	if _, err := f.GetByID(ctx, facilityID); err != nil {
+		return err
+	}
+
+	user, err := f.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return fmt.Errorf("%w: user not found", ErrInvalidFacility)
+	}
+
+	return f.facilityRepo.AddPartner(ctx, facilityID, userID)
+}
@@ -2,155 +2,666 @@ package usecase
 
 import (
 	"backend/internal/entity"
+	"backend/internal/infrastructure/push"
 	"backend/internal/repository"
-	"bytes"
+	"backend/pkg/bloodcompat"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"log"
 	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoDeviceToken berarti user tidak punya perangkat terdaftar untuk menerima push
+	ErrNoDeviceToken = errors.New("user has no registered device")
+	// ErrPushSuppressed berarti push ditahan preferensi user (quiet hours atau batas harian)
+	ErrPushSuppressed = errors.New("push suppressed by notification preference")
+)
 
-	firebase "firebase.google.com/go/v4"
-	"golang.org/x/oauth2/google"
+const (
+	// maxNotifiedDonors membatasi jumlah pendonor terdekat yang dihubungi per broadcast berbasis radius
+	maxNotifiedDonors = 500
+	// topicPageSize adalah jumlah pelanggan topic yang diambil dan dikirimi per halaman
+	topicPageSize = 500
+	// pushSendConcurrency membatasi jumlah request FCM yang berjalan paralel dalam satu pengiriman
+	pushSendConcurrency = 10
 )
 
 type fcmUseCase struct {
-	userRepo repository.UserRepository
+	pushProvider      push.PushProvider
+	userRepo          repository.UserRepository
+	bloodRequestRepo  repository.BloodRequestRepository
+	notificationRepo  repository.NotificationRepository
+	subscriptionRepo  repository.TopicSubscriptionRepository
+	outboxRepo        repository.OutboxRepository
+	deviceTokenRepo   repository.DeviceTokenRepository
+	preferenceUseCase NotificationPreferenceUseCase
+	messagingUseCase  MessagingUseCase
 }
 
-func NewFcmUseCase(userRepo repository.UserRepository) FCMUseCase {
+func NewFcmUseCase(
+	pushProvider push.PushProvider,
+	userRepo repository.UserRepository,
+	bloodRequestRepo repository.BloodRequestRepository,
+	notificationRepo repository.NotificationRepository,
+	subscriptionRepo repository.TopicSubscriptionRepository,
+	outboxRepo repository.OutboxRepository,
+	deviceTokenRepo repository.DeviceTokenRepository,
+	preferenceUseCase NotificationPreferenceUseCase,
+	messagingUseCase MessagingUseCase,
+) FCMUseCase {
 	return &fcmUseCase{
-		userRepo: userRepo,
+		pushProvider:      pushProvider,
+		userRepo:          userRepo,
+		bloodRequestRepo:  bloodRequestRepo,
+		notificationRepo:  notificationRepo,
+		subscriptionRepo:  subscriptionRepo,
+		outboxRepo:        outboxRepo,
+		deviceTokenRepo:   deviceTokenRepo,
+		preferenceUseCase: preferenceUseCase,
+		messagingUseCase:  messagingUseCase,
 	}
 }
 
-// GetAccessToken implements FCMUseCase.
-func (f *fcmUseCase) GetAccessToken(ctx context.Context) (*entity.Fcm, error) {
-	serviceAccountPath := "internal/infrastructure/broadcast/donora-f67f2-5c889d5acd0a.json"
-	data, err := os.ReadFile(serviceAccountPath)
+// validateBroadcast memastikan broadcast dikirim oleh pencari pemilik request yang masih aktif
+func (f *fcmUseCase) validateBroadcast(ctx context.Context, userID, bloodRequestID uint) (*entity.BloodRequest, *entity.User, error) {
+	request, err := f.bloodRequestRepo.FindById(ctx, bloodRequestID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	conf, err := google.JWTConfigFromJSON(data, "https://www.googleapis.com/auth/firebase.messaging")
-	if err != nil {
-		return nil, err
+	if request == nil {
+		return nil, nil, ErrBloodRequestNotFound
 	}
 
-	token, err := conf.TokenSource(ctx).Token()
-	if err != nil {
-		return nil, err
+	if request.UserID != userID {
+		return nil, nil, ErrBloodRequestForbidden
 	}
 
-	// ambil project_id dari file JSON
-	var parsed map[string]interface{}
-	if err := json.Unmarshal(data, &parsed); err != nil {
-		return nil, err
+	// Broadcast untuk request yang sudah ditutup tidak boleh dikirim lagi
+	if !request.Status.IsActive() {
+		return nil, nil, ErrBloodRequestClosed
 	}
 
-	projectID, ok := parsed["project_id"].(string)
-	if !ok {
-		return nil, fmt.Errorf("project_id not found or invalid")
+	if !bloodcompat.IsValidBloodType(request.BloodType) {
+		return nil, nil, fmt.Errorf("%w: invalid blood type", ErrInvalidBloodRequest)
 	}
 
-	res := &entity.Fcm{
-		AccessToken: token.AccessToken,
-		ProjectID:   projectID,
+	user, err := f.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return res, nil
+	if user == nil {
+		return nil, nil, errors.New("user not found")
+	}
+
+	if user.Role != "pencari" {
+		return nil, nil, ErrNotPencari
+	}
+
+	return request, user, nil
 }
 
 // SendFCMV1 implements FCMUseCase.
-func (f *fcmUseCase) SendFCMV1(ctx context.Context, userID uint, bloodType, title, body string) error {
-	data, err := f.GetAccessToken(ctx)
+// reached berisi user yang sudah dijangkau percobaan sebelumnya dan tidak dikirimi lagi.
+// User yang dijangkau dikembalikan walaupun sebagian pengiriman gagal.
+func (f *fcmUseCase) SendFCMV1(ctx context.Context, userID, bloodRequestID uint, title, body string, reached map[uint]bool) ([]uint, error) {
+	request, user, err := f.validateBroadcast(ctx, userID, bloodRequestID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	messageData := requesterMessageData(user, request.BloodType, request.ID)
+	messageData["urgency"] = strconv.Itoa(request.Urgency)
+
+	// Request yang punya koordinat hanya dikirim ke pendonor di sekitar lokasi
+	if request.Latitude != nil && request.Longitude != nil {
+		return f.notifyNearby(ctx, request, DefaultDonorRadiusKm, title, body, messageData, reached)
+	}
+
+	// Tanpa koordinat, kirim ke pendonor yang berlangganan topic golongan darah penerima
+	recipient := bloodcompat.Group{BloodType: request.BloodType, Rhesus: request.Rhesus}
+	return f.sendToTopicSubscribers(ctx, recipient, title, body, messageData, reached)
+}
+
+// SendToUser implements FCMUseCase.
+func (f *fcmUseCase) SendToUser(ctx context.Context, userID uint, title, body string, data map[string]string) error {
 	user, err := f.userRepo.FindById(ctx, userID)
 	if err != nil {
 		return err
 	}
 
-	if user.FCMToken == "" {
-		err = errors.New("user fcm token not found")
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	notified, err := f.sendToUsers(ctx, []uint{user.ID}, title, body, data)
+	if len(notified) == 0 {
 		return err
 	}
 
-	if user.Role != "pencari" {
-		err = errors.New("user role must be pencari")
+	f.saveToInbox(ctx, notified, title, body, data)
+	return nil
+}
+
+// SendChatToUser implements FCMUseCase.
+// Notifikasi chat tidak disimpan ke inbox karena pesannya sudah tersimpan di riwayat chat.
+func (f *fcmUseCase) SendChatToUser(ctx context.Context, userID uint, title, body, collapseKey string, data map[string]string) error {
+	message := push.Message{
+		Title:       title,
+		Body:        body,
+		Data:        data,
+		CollapseKey: collapseKey,
+	}
+
+	notified, err := f.sendMessageToUsers(ctx, []uint{userID}, message)
+	if len(notified) == 0 {
 		return err
 	}
 
-	url := fmt.Sprintf("https://fcm.googleapis.com/v1/projects/%s/messages:send", data.ProjectID)
-
-	payload := map[string]interface{}{
-		"message": map[string]interface{}{
-			"topic": "blood_" + bloodType,
-			"notification": map[string]string{
-				"title": title,
-				"body":  body,
-			},
-			"data": map[string]string{
-				"user_id":       strconv.FormatUint(uint64(userID), 10),
-				"profile_photo": *user.ProfilePhoto,
-				"name":          user.Name,
-			},
+	return nil
+}
+
+// NotifyNearbyDonors implements FCMUseCase.
+func (f *fcmUseCase) NotifyNearbyDonors(ctx context.Context, request *entity.BloodRequest, radiusKm float64, title, body string) (int, error) {
+	if !request.Status.IsActive() {
+		return 0, ErrBloodRequestClosed
+	}
+
+	requester, err := f.userRepo.FindById(ctx, request.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	if requester == nil {
+		return 0, errors.New("user not found")
+	}
+
+	messageData := requesterMessageData(requester, request.BloodType, request.ID)
+	messageData["urgency"] = strconv.Itoa(request.Urgency)
+	return partialSendResult(f.notifyNearby(ctx, request, radiusKm, title, body, messageData, nil))
+}
+
+// NotifyCompatibleGroups implements FCMUseCase.
+func (f *fcmUseCase) NotifyCompatibleGroups(ctx context.Context, request *entity.BloodRequest, title, body string) (int, error) {
+	if !request.Status.IsActive() {
+		return 0, ErrBloodRequestClosed
+	}
+
+	requester, err := f.userRepo.FindById(ctx, request.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	if requester == nil {
+		return 0, errors.New("user not found")
+	}
+
+	messageData := requesterMessageData(requester, request.BloodType, request.ID)
+	messageData["urgency"] = strconv.Itoa(request.Urgency)
+
+	// pendonor yang cocok sudah berlangganan topic penerima ini
+	recipient := bloodcompat.Group{BloodType: request.BloodType, Rhesus: request.Rhesus}
+	return partialSendResult(f.sendToTopicSubscribers(ctx, recipient, title, body, messageData, nil))
+}
+
+// partialSendResult dipakai pengiriman yang tidak melacak penerima per percobaan (eskalasi):
+// selama ada user yang dijangkau, kegagalan sebagian hanya dicatat agar user yang sudah
+// menerima tidak dikirimi ulang. Karena penerimanya tidak dilacak, push yang ditahan preferensi
+// tidak ditunda, baik sebagian maupun seluruhnya.
+func partialSendResult(notified []uint, err error) (int, error) {
+	if errors.Is(err, ErrPushSuppressed) {
+		err = nil
+	}
+
+	if len(notified) == 0 {
+		return 0, err
+	}
+
+	if err != nil {
+		log.Printf("Failed to notify some recipients: %v", err)
+	}
+	return len(notified), nil
+}
+
+// retryableSendError membuang error pengiriman ke banyak user yang tidak perlu dicoba ulang:
+// user tanpa perangkat dan token yang sudah dihapus karena ditolak FCM. ErrPushSuppressed tetap
+// dikembalikan agar outbox menunda user yang push-nya ditahan preferensi.
+func retryableSendError(err error) error {
+	if errors.Is(err, ErrNoDeviceToken) || errors.Is(err, push.ErrInvalidToken) {
+		return nil
+	}
+	return err
+}
+
+// worseSendError memilih error yang menentukan hasil pengiriman beberapa batch: kegagalan yang
+// perlu dicoba ulang lebih penting daripada push yang ditahan preferensi
+func worseSendError(current, next error) error {
+	if next == nil || (current != nil && !errors.Is(current, ErrPushSuppressed)) {
+		return current
+	}
+	return next
+}
+
+// withoutReached membuang user yang sudah dijangkau percobaan sebelumnya
+func withoutReached(userIDs []uint, reached map[uint]bool) []uint {
+	if len(reached) == 0 {
+		return userIDs
+	}
+
+	remaining := make([]uint, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if !reached[userID] {
+			remaining = append(remaining, userID)
+		}
+	}
+	return remaining
+}
+
+// sendToTopicSubscribers mengirim ke setiap pelanggan topic golongan darah recipient satu per satu,
+// bukan lewat FCM topic, agar preferensi notifikasi masing-masing user tetap berlaku. Pelanggan
+// diambil per halaman sampai habis. Untuk request urgent, pendonor yang cocok dan opt-in SMS /
+// WhatsApp ikut dihubungi walaupun tidak punya perangkat untuk berlangganan topic.
+func (f *fcmUseCase) sendToTopicSubscribers(ctx context.Context, recipient bloodcompat.Group, title, body string, data map[string]string, reached map[uint]bool) ([]uint, error) {
+	topics := []string{bloodcompat.Topic(recipient)}
+	pages := []func(afterUserID uint) ([]uint, error){
+		func(afterUserID uint) ([]uint, error) {
+			return f.subscriptionRepo.FindUserIDsByTopics(ctx, topics, afterUserID, topicPageSize)
 		},
 	}
 
-	payloadBytes, _ := json.Marshal(payload)
+	if usesTextFallback(data) {
+		groups := bloodcompat.DonorsFor(recipient)
+		pages = append(pages, func(afterUserID uint) ([]uint, error) {
+			return f.messagingUseCase.FindFallbackDonors(ctx, groups, afterUserID, topicPageSize)
+		})
+	}
+
+	var (
+		notified []uint
+		sendErr  error
+	)
+	attempted := map[uint]bool{}
+
+	for _, page := range pages {
+		var afterUserID uint
+		for {
+			userIDs, err := page(afterUserID)
+			if err != nil {
+				return notified, err
+			}
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(payloadBytes))
-	req.Header.Set("Authorization", "Bearer "+data.AccessToken)
-	req.Header.Set("Content-Type", "application/json")
+			var batch []uint
+			for _, userID := range userIDs {
+				if !reached[userID] && !attempted[userID] {
+					attempted[userID] = true
+					batch = append(batch, userID)
+				}
+			}
+
+			if len(batch) > 0 {
+				sent, err := f.sendToUsers(ctx, batch, title, body, data)
+				f.saveToInbox(ctx, sent, title, body, data)
+				notified = append(notified, sent...)
+				sendErr = worseSendError(sendErr, retryableSendError(err))
+			}
+
+			if len(userIDs) < topicPageSize {
+				break
+			}
+			afterUserID = userIDs[len(userIDs)-1]
+		}
+	}
+
+	return notified, sendErr
+}
 
-	client := &http.Client{}
-	res, err := client.Do(req)
+// NotifyAdmins implements FCMUseCase.
+func (f *fcmUseCase) NotifyAdmins(ctx context.Context, request *entity.BloodRequest, title, body string) (int, error) {
+	admins, err := f.userRepo.FindByRole(ctx, "admin")
 	if err != nil {
-		return err
+		return 0, err
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != 200 {
-		bodyBytes, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("failed to send: %s", string(bodyBytes))
+	messageData := map[string]string{
+		"type":             string(entity.NotificationAdminAlert),
+		"blood_request_id": strconv.FormatUint(uint64(request.ID), 10),
+		"blood_type":       request.BloodType,
 	}
 
-	return nil
+	adminIDs := make([]uint, 0, len(admins))
+	for _, admin := range admins {
+		adminIDs = append(adminIDs, admin.ID)
+	}
+
+	notified, err := f.sendToUsers(ctx, adminIDs, title, body, messageData)
+	if err != nil {
+		log.Printf("Failed to notify some admins for blood request %d: %v", request.ID, err)
+	}
+
+	f.saveToInbox(ctx, notified, title, body, messageData)
+	return len(notified), nil
 }
 
-// func (f *fcmUseCase) SubscribeByBlood(ctx context.Context, fcmToken, bloodType string) error {
-// 	opt := option.WithCredentialsFile("/home/fahrul/gdsc/gsc/backend/internal/infrastructure/broadcast/donora-f67f2-5c889d5acd0a.json")
+// notifyNearby mengirim notifikasi langsung ke token pendonor yang cocok dalam radius
+func (f *fcmUseCase) notifyNearby(ctx context.Context, request *entity.BloodRequest, radiusKm float64, title, body string, messageData map[string]string, reached map[uint]bool) ([]uint, error) {
+	if request.Latitude == nil || request.Longitude == nil {
+		return nil, fmt.Errorf("%w: blood request has no coordinates", ErrInvalidBloodRequest)
+	}
 
-// 	app, err := firebase.NewApp(ctx, nil, opt)
-// 	if err != nil {
-// 		return err
-// 	}
+	groups := bloodcompat.DonorsFor(bloodcompat.Group{BloodType: request.BloodType, Rhesus: request.Rhesus})
+	notified, total, err := f.notifyDonorsNear(ctx, *request.Latitude, *request.Longitude, radiusKm, groups, title, body, messageData, reached)
 
-// 	err = SubscribeUserToBloodTopic(ctx, app, fcmToken, bloodType)
-// 	if err != nil {
-// 		return err
-// 	}
+	log.Printf("Blood request %d notified %d of %d donors within %.0f km", request.ID, len(notified), total, radiusKm)
+	return notified, err
+}
 
-// 	return nil
-// }
+// NotifyDonorsNear implements FCMUseCase.
+func (f *fcmUseCase) NotifyDonorsNear(ctx context.Context, latitude, longitude, radiusKm float64, groups []bloodcompat.Group, title, body string, data map[string]string, reached map[uint]bool) ([]uint, error) {
+	notified, _, err := f.notifyDonorsNear(ctx, latitude, longitude, radiusKm, groups, title, body, data, reached)
+	return notified, err
+}
 
-func (f *fcmUseCase) SubscribeUserToBloodTopic(ctx context.Context, app *firebase.App, fcmToken string, bloodType string) error {
-	client, err := app.Messaging(ctx)
+// notifyDonorsNear mengembalikan pendonor yang dijangkau dan jumlah pendonor yang ditemukan.
+// Satu token gagal tidak menghentikan pengiriman ke pendonor lain, tetapi error-nya tetap
+// dikembalikan agar pemanggil bisa mencoba ulang pendonor yang belum dijangkau.
+func (f *fcmUseCase) notifyDonorsNear(ctx context.Context, latitude, longitude, radiusKm float64, groups []bloodcompat.Group, title, body string, messageData map[string]string, reached map[uint]bool) ([]uint, int, error) {
+	donors, err := f.userRepo.FindCompatibleDonorsNear(ctx, latitude, longitude, radiusKm, groups, maxNotifiedDonors)
 	if err != nil {
+		return nil, 0, err
+	}
+
+	donorIDs := make([]uint, 0, len(donors))
+	for _, donor := range donors {
+		donorIDs = append(donorIDs, donor.UserID)
+	}
+
+	donorIDs = withoutReached(donorIDs, reached)
+	if len(donorIDs) == 0 {
+		return nil, len(donors), nil
+	}
+
+	notified, err := f.sendToUsers(ctx, donorIDs, title, body, messageData)
+	f.saveToInbox(ctx, notified, title, body, messageData)
+	return notified, len(donors), retryableSendError(err)
+}
+
+// sendToUsers mengirim message ke semua perangkat milik userIDs dan mengembalikan user yang
+// minimal satu perangkatnya menerima notifikasi, beserta error terakhir jika ada yang gagal.
+// User yang push-nya ditahan preferensi (quiet hours atau batas harian) tidak dihitung sebagai
+// penerima; jika ada yang ditahan dan tidak ada kegagalan lain, error-nya ErrPushSuppressed.
+// Token yang ditolak FCM langsung dihapus.
+func (f *fcmUseCase) sendToUsers(ctx context.Context, userIDs []uint, title, body string, data map[string]string) ([]uint, error) {
+	return f.sendMessageToUsers(ctx, userIDs, push.Message{Title: title, Body: body, Data: data})
+}
+
+// sendMessageToUsers sama dengan sendToUsers, tetapi memakai template message sehingga
+// field seperti CollapseKey ikut terkirim ke setiap perangkat
+func (f *fcmUseCase) sendMessageToUsers(ctx context.Context, userIDs []uint, template push.Message) ([]uint, error) {
+	notified, suppressed, err := f.pushToUsers(ctx, userIDs, template)
+
+	bloodRequestID, _ := strconv.ParseUint(template.Data["blood_request_id"], 10, 64)
+	if bloodRequestID == 0 || !usesTextFallback(template.Data) {
+		return notified, suppressedSendError(err, suppressed)
+	}
+
+	// request urgent yang tidak sampai lewat push dikirim ulang lewat SMS / WhatsApp;
+	// user yang push-nya ditahan batas harian juga tidak dikirimi SMS
+	reached := make(map[uint]bool, len(notified)+len(suppressed))
+	for _, userID := range notified {
+		reached[userID] = true
+	}
+	for _, userID := range suppressed {
+		reached[userID] = true
+	}
+
+	var unreached []uint
+	for _, userID := range userIDs {
+		if !reached[userID] {
+			reached[userID] = true
+			unreached = append(unreached, userID)
+		}
+	}
+
+	texted := f.messagingUseCase.SendUrgentFallback(ctx, unreached, uint(bloodRequestID), template.Title, template.Body)
+	if len(texted) > 0 && errors.Is(err, ErrNoDeviceToken) {
+		err = nil
+	}
+
+	return append(notified, texted...), suppressedSendError(err, suppressed)
+}
+
+// suppressedSendError mengembalikan ErrPushSuppressed jika ada user yang push-nya ditahan
+// preferensi, baik sebagian maupun seluruhnya, selama tidak ada kegagalan lain yang perlu
+// dicoba ulang. Dengan begitu outbox selalu menunda message yang penerimanya ditahan.
+func suppressedSendError(err error, suppressed []uint) error {
+	if len(suppressed) == 0 {
 		return err
 	}
 
-	topic := "blood_" + bloodType
-	_, err = client.SubscribeToTopic(ctx, []string{fcmToken}, topic)
+	if err == nil || errors.Is(err, ErrNoDeviceToken) || errors.Is(err, push.ErrInvalidToken) {
+		return ErrPushSuppressed
+	}
+	return err
+}
+
+// pushToUsers mengirim push ke semua perangkat userIDs sesuai preferensi notifikasi dan
+// mengembalikan user yang menerima push serta user yang push-nya ditahan preferensi.
+// Pengiriman ke perangkat berjalan paralel dengan batas pushSendConcurrency.
+func (f *fcmUseCase) pushToUsers(ctx context.Context, userIDs []uint, template push.Message) ([]uint, []uint, error) {
+	tokens, err := f.deviceTokenRepo.FindByUserIDs(ctx, userIDs)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	return nil
+	if len(tokens) == 0 {
+		return nil, nil, ErrNoDeviceToken
+	}
+
+	owners := make([]uint, 0, len(tokens))
+	for _, token := range tokens {
+		owners = append(owners, token.UserID)
+	}
+
+	now := time.Now()
+	allowedIDs, suppressed, err := f.preferenceUseCase.Filter(ctx, owners, notificationCategory(template.Data), now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allowed := make(map[uint]bool, len(allowedIDs))
+	for _, userID := range allowedIDs {
+		allowed[userID] = true
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		lastErr error
+		invalid []*entity.DeviceToken
+	)
+	received := map[uint]bool{}
+	slots := make(chan struct{}, pushSendConcurrency)
+
+	for _, token := range tokens {
+		if !allowed[token.UserID] {
+			continue
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(token *entity.DeviceToken) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			message := template
+			message.Token = token.Token
+			err := f.pushProvider.Send(ctx, message)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err == nil {
+				received[token.UserID] = true
+				return
+			}
+
+			// error sementara diutamakan agar pengiriman ke banyak user tetap dicoba ulang
+			if lastErr == nil || !errors.Is(err, push.ErrInvalidToken) {
+				lastErr = err
+			}
+
+			if errors.Is(err, push.ErrInvalidToken) {
+				invalid = append(invalid, token)
+				return
+			}
+
+			log.Printf("Failed to notify device %d of user %d: %v", token.ID, token.UserID, err)
+		}(token)
+	}
+	wg.Wait()
+
+	// token dihapus setelah semua pengiriman selesai agar query database tidak berjalan paralel
+	for _, token := range invalid {
+		f.pruneToken(ctx, token)
+	}
+
+	var notified, failed []uint
+	for _, userID := range allowedIDs {
+		if received[userID] {
+			notified = append(notified, userID)
+		} else {
+			failed = append(failed, userID)
+		}
+	}
+
+	// jatah harian user yang tidak menerima satu pun push dikembalikan
+	if len(failed) > 0 {
+		if err := f.preferenceUseCase.Release(ctx, failed, now); err != nil {
+			log.Printf("Failed to release push quota of %d user(s): %v", len(failed), err)
+		}
+	}
+
+	return notified, suppressed, lastErr
+}
+
+// pruneToken menghapus token yang sudah di-uninstall atau tidak valid beserta langganan topic-nya
+func (f *fcmUseCase) pruneToken(ctx context.Context, token *entity.DeviceToken) {
+	log.Printf("Pruning invalid device token %d of user %d", token.ID, token.UserID)
+
+	if err := f.deviceTokenRepo.DeleteByToken(ctx, token.Token); err != nil {
+		log.Printf("Failed to delete device token %d: %v", token.ID, err)
+	}
+
+	if err := f.subscriptionRepo.DeleteByToken(ctx, token.Token); err != nil {
+		log.Printf("Failed to delete topic subscriptions of device token %d: %v", token.ID, err)
+	}
+}
+
+func requesterMessageData(requester *entity.User, bloodType string, bloodRequestID uint) map[string]string {
+	var profilePhoto string
+	if requester.ProfilePhoto != nil {
+		profilePhoto = *requester.ProfilePhoto
+	}
+
+	messageData := map[string]string{
+		"type":          string(entity.NotificationBloodRequest),
+		"user_id":       strconv.FormatUint(uint64(requester.ID), 10),
+		"profile_photo": profilePhoto,
+		"name":          requester.Name,
+		"blood_type":    bloodType,
+	}
+
+	if bloodRequestID != 0 {
+		messageData["blood_request_id"] = strconv.FormatUint(uint64(bloodRequestID), 10)
+	}
+
+	return messageData
+}
+
+// saveToInbox menyimpan notifikasi yang sudah terkirim ke inbox penerima.
+// Gagal menyimpan hanya dicatat di log karena push-nya sudah terkirim.
+func (f *fcmUseCase) saveToInbox(ctx context.Context, userIDs []uint, title, body string, data map[string]string) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	notificationType := entity.NotificationType(data["type"])
+	if notificationType == "" {
+		notificationType = entity.NotificationGeneral
+	}
+
+	payload := data
+	if payload == nil {
+		payload = map[string]string{}
+	}
+
+	notification := &entity.Notification{
+		Type:    notificationType,
+		Title:   title,
+		Body:    body,
+		Payload: payload,
+		Target:  notificationTarget(data),
+	}
+
+	if err := f.notificationRepo.CreateForUsers(ctx, userIDs, notification); err != nil {
+		log.Printf("Failed to save notification %q to inbox of %d user(s): %v", title, len(userIDs), err)
+	}
+}
+
+// notificationTarget adalah deep link yang dibuka aplikasi saat notifikasi di inbox diketuk
+func notificationTarget(data map[string]string) string {
+	if id := data["blood_request_id"]; id != "" {
+		return "/blood-request?id=" + id
+	}
+
+	if id := data["facility_id"]; id != "" {
+		return "/facility?id=" + id
+	}
+
+	return ""
+}
+
+// usesTextFallback menandakan notifikasi request urgent untuk pendonor yang dikirim ulang lewat
+// SMS / WhatsApp jika push tidak sampai. Alert admin tidak ikut walaupun kategorinya sama.
+func usesTextFallback(data map[string]string) bool {
+	return entity.NotificationType(data["type"]) == entity.NotificationBloodRequest &&
+		notificationCategory(data) == entity.CategoryUrgentRequest
+}
+
+// notificationCategory menentukan kategori preferensi dari tipe notifikasi. Notifikasi umum
+// tidak punya kategori, sehingga hanya dibatasi quiet hours dan batas harian.
+func notificationCategory(data map[string]string) entity.NotificationCategory {
+	switch entity.NotificationType(data["type"]) {
+	case entity.NotificationBloodRequest:
+		if urgency, _ := strconv.Atoi(data["urgency"]); urgency >= entity.UrgencyUrgent {
+			return entity.CategoryUrgentRequest
+		}
+		return entity.CategoryMatchingRequest
+	case entity.NotificationAdminAlert:
+		return entity.CategoryUrgentRequest
+	case entity.NotificationDonation, entity.NotificationStockShortage:
+		return entity.CategoryMatchingRequest
+	case entity.NotificationChatMessage:
+		return entity.CategoryChat
+	case entity.NotificationDonationReminder:
+		return entity.CategoryReminder
+	}
+	return ""
+}
+
+// SubscribeToTopic implements FCMUseCase.
+func (f *fcmUseCase) SubscribeToTopic(ctx context.Context, tokens []string, topic string) error {
+	return f.pushProvider.SubscribeToTopic(ctx, tokens, topic)
+}
+
+// UnsubscribeFromTopic implements FCMUseCase.
+func (f *fcmUseCase) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) error {
+	return f.pushProvider.UnsubscribeFromTopic(ctx, tokens, topic)
 }
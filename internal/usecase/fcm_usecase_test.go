@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"backend/internal/infrastructure/push"
+	"errors"
+	"testing"
+)
+
+func TestSuppressedSendError(t *testing.T) {
+	errTransient := errors.New("unavailable")
+
+	tests := []struct {
+		name       string
+		err        error
+		suppressed []uint
+		want       error
+	}{
+		{"nothing suppressed", nil, nil, nil},
+		{"nothing suppressed keeps error", errTransient, nil, errTransient},
+		{"partially suppressed", nil, []uint{2}, ErrPushSuppressed},
+		{"suppressed and no device", ErrNoDeviceToken, []uint{2}, ErrPushSuppressed},
+		{"suppressed and invalid token", push.ErrInvalidToken, []uint{2}, ErrPushSuppressed},
+		{"transient error wins", errTransient, []uint{2}, errTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suppressedSendError(tt.err, tt.suppressed); got != tt.want {
+				t.Errorf("suppressedSendError(%v, %v) = %v, want %v", tt.err, tt.suppressed, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorseSendError(t *testing.T) {
+	errTransient := errors.New("unavailable")
+
+	tests := []struct {
+		name          string
+		current, next error
+		want          error
+	}{
+		{"both nil", nil, nil, nil},
+		{"first error", nil, ErrPushSuppressed, ErrPushSuppressed},
+		{"keeps error when next is nil", ErrPushSuppressed, nil, ErrPushSuppressed},
+		{"transient replaces suppressed", ErrPushSuppressed, errTransient, errTransient},
+		{"suppressed does not replace transient", errTransient, ErrPushSuppressed, errTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := worseSendError(tt.current, tt.next); got != tt.want {
+				t.Errorf("worseSendError(%v, %v) = %v, want %v", tt.current, tt.next, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,37 +5,163 @@ import (
 	"backend/internal/infrastructure/storage"
 	"backend/internal/repository"
 	"context"
+	"fmt"
+	"strconv"
 	"time"
 )
 
+// donationCoin adalah coin yang didapat pendonor untuk setiap donasi
+const donationCoin = 10
+
 type historyUseCase struct {
-	historyRepo repository.HistoriesRepository
-	fileStorage storage.FileStorage
+	txManager        repository.TxManager
+	historyRepo      repository.HistoriesRepository
+	userRepo         repository.UserRepository
+	bloodRequestRepo repository.BloodRequestRepository
+	pledgeRepo       repository.PledgeRepository
+	facilityRepo     repository.FacilityRepository
+	outboxRepo       repository.OutboxRepository
+	fileStorage      storage.FileStorage
 }
 
-func NewHistoryUseCase(historyRepo repository.HistoriesRepository, fileStorage storage.FileStorage) HistoryUseCase {
+func NewHistoryUseCase(
+	txManager repository.TxManager,
+	historyRepo repository.HistoriesRepository,
+	userRepo repository.UserRepository,
+	bloodRequestRepo repository.BloodRequestRepository,
+	pledgeRepo repository.PledgeRepository,
+	facilityRepo repository.FacilityRepository,
+	outboxRepo repository.OutboxRepository,
+	fileStorage storage.FileStorage,
+) HistoryUseCase {
 	return &historyUseCase{
-		historyRepo: historyRepo,
-		fileStorage: fileStorage,
+		txManager:        txManager,
+		historyRepo:      historyRepo,
+		userRepo:         userRepo,
+		bloodRequestRepo: bloodRequestRepo,
+		pledgeRepo:       pledgeRepo,
+		facilityRepo:     facilityRepo,
+		outboxRepo:       outboxRepo,
+		fileStorage:      fileStorage,
 	}
 }
 
 // AddHistory implements HistoryUseCase.
-func (h *historyUseCase) AddHistory(ctx context.Context, userID uint, bloodRequestID uint, imageDonor string, nextDonation time.Time) error {
+// History, total donasi, coin, sisa kebutuhan blood request dan notifikasi pencari ditulis dalam satu transaksi.
+func (h *historyUseCase) AddHistory(ctx context.Context, userID uint, bloodRequestID uint, facilityID *uint, imageDonor string, nextDonation time.Time) error {
+	if facilityID != nil {
+		facility, err := h.facilityRepo.FindById(ctx, *facilityID)
+		if err != nil {
+			return err
+		}
+
+		if facility == nil {
+			return ErrFacilityNotFound
+		}
+	}
+
 	history := &entity.History{
 		UserID:         userID,
 		BloodRequestID: bloodRequestID,
+		FacilityID:     facilityID,
 		ImageDonor:     imageDonor,
 		NextDonation:   nextDonation,
 	}
 
-	err := h.historyRepo.Create(ctx, history)
+	return h.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		request, err := h.bloodRequestRepo.FindByIdForUpdate(ctx, bloodRequestID)
+		if err != nil {
+			return err
+		}
+
+		if request == nil {
+			return ErrBloodRequestNotFound
+		}
+
+		// tempat donor mengikuti fasilitas blood request jika tidak diisi
+		if history.FacilityID == nil {
+			history.FacilityID = request.FacilityID
+		}
+
+		pledge, err := h.pledgeRepo.FindByBloodRequestAndDonor(ctx, bloodRequestID, userID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		// donasi dianggap terverifikasi jika pendonor punya pledge yang sudah diterima pencari;
+		// pledge langsung dipindah ke donated agar tidak bisa dipakai untuk mencatat donasi lagi
+		if pledge != nil && pledge.Status == entity.PledgeAccepted && request.Status.IsActive() && request.Total > 0 {
+			consumed, err := h.pledgeRepo.UpdateStatus(ctx, pledge.ID, entity.PledgeAccepted, entity.PledgeDonated, now)
+			if err != nil {
+				return err
+			}
+			history.Verified = consumed
+		}
+
+		if err := h.historyRepo.Create(ctx, history); err != nil {
+			return err
+		}
+
+		// coin hanya diberikan untuk donasi yang terverifikasi
+		coin := 0
+		if history.Verified {
+			coin = donationCoin
+		}
+
+		if err := h.userRepo.AddDonation(ctx, userID, coin); err != nil {
+			return err
+		}
+
+		if !history.Verified {
+			return nil
+		}
+
+		remaining, err := h.bloodRequestRepo.DecrementTotal(ctx, request.ID, now)
+		if err != nil {
+			return err
+		}
+
+		if remaining == 0 {
+			ok, err := h.bloodRequestRepo.UpdateStatus(ctx, request.ID, request.Status, entity.BloodRequestFulfilled, now)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				return ErrInvalidTransition
+			}
+		}
+
+		return h.notifyRequester(ctx, request, remaining)
+	})
+}
+
+// notifyRequester menulis notifikasi untuk pencari ke outbox di transaksi yang sama dengan donasi
+func (h *historyUseCase) notifyRequester(ctx context.Context, request *entity.BloodRequest, remaining int) error {
+	title := "Donor darah tercatat"
+	body := fmt.Sprintf("Satu kantong darah untuk %s telah didonorkan, masih dibutuhkan %d kantong", request.SearchName, remaining)
+	if remaining == 0 {
+		title = "Kebutuhan darah terpenuhi"
+		body = fmt.Sprintf("Seluruh kebutuhan darah untuk %s telah terpenuhi", request.SearchName)
+	}
+
+	data := map[string]string{
+		"type":             string(entity.NotificationDonation),
+		"blood_request_id": strconv.FormatUint(uint64(request.ID), 10),
+		"remaining":        strconv.Itoa(remaining),
+	}
 
-	if err != nil {
-		return err
+	payload := entity.PushUserPayload{
+		UserID: request.UserID,
+		Title:  title,
+		Body:   body,
+		Data:   data,
 	}
 
-	return nil
+	_, err := enqueueOutbox(ctx, h.outboxRepo, entity.OutboxPushUser, payload)
+	return err
 }
 
 // HistoryByUserId implements HistoryUseCase.
@@ -3,11 +3,10 @@ package usecase
 import (
 	"backend/internal/entity"
 	"backend/internal/infrastructure/oauth"
+	"backend/pkg/bloodcompat"
 	"context"
 	"mime/multipart"
 	"time"
-
-	firebase "firebase.google.com/go/v4"
 )
 
 type AuthUseCase interface {
@@ -23,11 +22,14 @@ type AuthUseCase interface {
 	UpdateCountDonation(ctx context.Context, userID uint, total int) error
 	UpdateCoinTotal(ctx context.Context, userID uint, coin int) error
 	Logout(ctx context.Context, userID uint, token string) error
+	IssueWebSocketTicket(ctx context.Context, userID uint) (*entity.Token, error)
 }
 
 type ProfileUseCase interface {
 	UpdateProfilePhoto(ctx context.Context, userID uint, file multipart.File, fileHeader *multipart.FileHeader) (string, error)
 	GetUserProfile(ctx context.Context, userID uint) (*entity.User, error)
+	UpdateLocation(ctx context.Context, userID uint, latitude, longitude float64) error
+	UpdateBloodType(ctx context.Context, userID uint, bloodType, rhesus string) error
 }
 
 type EducationUseCase interface {
@@ -45,7 +47,7 @@ type EvidenceUseCase interface {
 }
 
 type HistoryUseCase interface {
-	AddHistory(ctx context.Context, userID, bloodRequestID uint, imageDonor string, nextDonation time.Time) error
+	AddHistory(ctx context.Context, userID, bloodRequestID uint, facilityID *uint, imageDonor string, nextDonation time.Time) error
 	HistoryByUserId(ctx context.Context, userID uint) ([]*entity.History, error)
 	GetNextDonation(ctx context.Context, userID uint) (date time.Time, err error)
 	GetLatestDonation(ctx context.Context, userID uint) (date time.Time, err error)
@@ -62,15 +64,127 @@ type RewardUseCase interface {
 }
 
 type FCMUseCase interface {
-	GetAccessToken(ctx context.Context) (*entity.Fcm, error)
-	SendFCMV1(ctx context.Context, userID uint, bloodType, title, body string) error
-	SubscribeUserToBloodTopic(ctx context.Context, app *firebase.App, fcmToken string, bloodType string) error
+	SendFCMV1(ctx context.Context, userID, bloodRequestID uint, title, body string, reached map[uint]bool) ([]uint, error)
+	SendToUser(ctx context.Context, userID uint, title, body string, data map[string]string) error
+	SendChatToUser(ctx context.Context, userID uint, title, body, collapseKey string, data map[string]string) error
+	NotifyNearbyDonors(ctx context.Context, request *entity.BloodRequest, radiusKm float64, title, body string) (int, error)
+	NotifyCompatibleGroups(ctx context.Context, request *entity.BloodRequest, title, body string) (int, error)
+	NotifyAdmins(ctx context.Context, request *entity.BloodRequest, title, body string) (int, error)
+	NotifyDonorsNear(ctx context.Context, latitude, longitude, radiusKm float64, groups []bloodcompat.Group, title, body string, data map[string]string, reached map[uint]bool) ([]uint, error)
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) error
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) error
+}
+
+type BroadcastUseCase interface {
+	Queue(ctx context.Context, userID, bloodRequestID uint) (*entity.BloodBroadcast, error)
+	GetKillSwitch(ctx context.Context, adminID uint) (*entity.FeatureFlag, error)
+	SetKillSwitch(ctx context.Context, adminID uint, disabled bool) (*entity.FeatureFlag, error)
+}
+
+type MessagingUseCase interface {
+	GetPreference(ctx context.Context, userID uint) (*entity.MessagingPreference, error)
+	OptIn(ctx context.Context, userID uint, channel string) (*entity.MessagingPreference, error)
+	OptOut(ctx context.Context, userID uint) (*entity.MessagingPreference, error)
+	SendUrgentFallback(ctx context.Context, userIDs []uint, bloodRequestID uint, title, body string) []uint
+	FindFallbackDonors(ctx context.Context, groups []bloodcompat.Group, afterUserID uint, limit int) ([]uint, error)
+	DeliverText(ctx context.Context, id uint) error
+	UpdateDeliveryStatus(ctx context.Context, providerMessageID string, status entity.TextMessageStatus, errMessage string) error
+}
+
+type NotificationPreferenceUseCase interface {
+	Get(ctx context.Context, userID uint) (*entity.NotificationPreference, error)
+	Update(ctx context.Context, userID uint, preference *entity.NotificationPreference) (*entity.NotificationPreference, error)
+	Filter(ctx context.Context, userIDs []uint, category entity.NotificationCategory, now time.Time) ([]uint, []uint, error)
+	Release(ctx context.Context, userIDs []uint, now time.Time) error
+}
+
+type ChatNotificationUseCase interface {
+	NotifyOffline(ctx context.Context, senderID, receiverID uint, content string)
+	CancelPending(receiverID uint)
 }
 
 type MessageUseCase interface {
-	SaveMessage(ctx context.Context, senderID uint, receiverID uint, content string) error
+	SaveMessage(ctx context.Context, senderID uint, receiverID uint, content, clientMsgID string) (*entity.Message, bool, error)
 	GetMessagesByUserID(ctx context.Context, userID1 uint, userID2 uint, limit int, offset int) ([]entity.Message, error)
 	GetUndeliveredMessages(ctx context.Context, receiverID uint) ([]entity.Message, error)
 	MarkMessageAsDelivered(ctx context.Context, messageID uint) error
-	GetLastMessage(ctx context.Context, senderID uint, receiverID uint) (*entity.Message, error)
+	MarkRead(ctx context.Context, readerID, senderID, messageID uint) (*time.Time, error)
+	ListConversations(ctx context.Context, userID, cursor uint, limit int) (*entity.ConversationPage, error)
+	GetConversation(ctx context.Context, userID, peerID uint) (*entity.Conversation, error)
+}
+
+type BloodRequestUseCase interface {
+	Create(ctx context.Context, userID uint, searchName, location string, facilityID *uint, latitude, longitude *float64, bloodType, rhesus string, total, urgency int, deadline *time.Time) (*entity.BloodRequest, error)
+	GetByID(ctx context.Context, id uint) (*entity.BloodRequest, error)
+	GetByUserID(ctx context.Context, userID uint) ([]*entity.BloodRequest, error)
+	Update(ctx context.Context, userID, id uint, searchName, location string, facilityID *uint, latitude, longitude *float64, bloodType, rhesus string, total, urgency int, deadline *time.Time) (*entity.BloodRequest, error)
+	Cancel(ctx context.Context, userID, id uint) error
+	Fulfill(ctx context.Context, userID, id uint) error
+	FindNearbyDonors(ctx context.Context, userID, id uint, radiusKm float64) ([]*entity.NearbyDonor, error)
+	Feed(ctx context.Context, userID uint, cursor string, limit int) (*entity.BloodRequestFeed, error)
+	Transition(ctx context.Context, id uint, to entity.BloodRequestStatus) (*entity.BloodRequest, error)
+	ExpireOverdue(ctx context.Context) (int, error)
+	RunExpirySweeper(ctx context.Context, interval time.Duration)
+}
+
+type EscalationUseCase interface {
+	EscalateDue(ctx context.Context) (int, error)
+	GetByBloodRequestID(ctx context.Context, userID, bloodRequestID uint) ([]*entity.BloodRequestEscalation, error)
+	DeliverEscalation(ctx context.Context, escalationID uint) error
+	RunEscalationScheduler(ctx context.Context, interval time.Duration)
+}
+
+type DonationReminderUseCase interface {
+	SendDue(ctx context.Context) (int, error)
+	RunReminderScheduler(ctx context.Context, interval time.Duration)
+}
+
+type FacilityUseCase interface {
+	Create(ctx context.Context, userID uint, facility *entity.Facility) error
+	GetByID(ctx context.Context, id uint) (*entity.Facility, error)
+	GetAll(ctx context.Context, facilityType entity.FacilityType) ([]*entity.Facility, error)
+	Update(ctx context.Context, userID uint, facility *entity.Facility) error
+	Delete(ctx context.Context, userID, id uint) error
+	FindNearest(ctx context.Context, latitude, longitude, radiusKm float64, limit int) ([]*entity.NearbyFacility, error)
+	AddPartner(ctx context.Context, adminID, facilityID, userID uint) error
+}
+
+type FacilityStockUseCase interface {
+	ReportStock(ctx context.Context, userID, facilityID uint, levels []entity.StockLevel) ([]*entity.FacilityStock, error)
+	GetStocks(ctx context.Context, facilityID uint) ([]*entity.FacilityStock, error)
+	GetHistory(ctx context.Context, facilityID uint, limit int) ([]*entity.FacilityStockHistory, error)
+}
+
+type OutboxUseCase interface {
+	DeliverDue(ctx context.Context) (int, error)
+	RunOutboxWorker(ctx context.Context, interval time.Duration)
+	GetMessages(ctx context.Context, adminID uint, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error)
+	Replay(ctx context.Context, adminID, id uint) (*entity.OutboxMessage, error)
+}
+
+type NotificationUseCase interface {
+	List(ctx context.Context, userID, cursor uint, limit int) (*entity.NotificationPage, error)
+	MarkRead(ctx context.Context, userID, id uint) error
+	MarkAllRead(ctx context.Context, userID uint) error
+	UnreadCount(ctx context.Context, userID uint) (int, error)
+}
+
+type DeviceTokenUseCase interface {
+	Register(ctx context.Context, userID uint, token string, platform entity.DevicePlatform, appVersion string) (*entity.DeviceToken, error)
+	Unregister(ctx context.Context, userID uint, token string) error
+	GetByUserID(ctx context.Context, userID uint) ([]*entity.DeviceToken, error)
+}
+
+type TopicSubscriptionUseCase interface {
+	Sync(ctx context.Context, userID uint) error
+	Release(ctx context.Context, token string) error
+}
+
+type PledgeUseCase interface {
+	Pledge(ctx context.Context, donorID, bloodRequestID uint) (*entity.Pledge, error)
+	Accept(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, error)
+	Decline(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, error)
+	MarkNoShow(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, error)
+	GetByBloodRequestID(ctx context.Context, requesterID, bloodRequestID uint) ([]*entity.Pledge, error)
+	GetByDonorID(ctx context.Context, donorID uint) ([]*entity.Pledge, error)
 }
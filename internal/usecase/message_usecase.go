@@ -4,8 +4,20 @@ import (
 	"backend/internal/entity"
 	"backend/internal/repository"
 	"context"
+	"errors"
+	"time"
 )
 
+const (
+	// maxClientMsgIDLength sama dengan panjang kolom messages.client_msg_id
+	maxClientMsgIDLength = 64
+
+	defaultConversationLimit = 20
+	maxConversationLimit     = 50
+)
+
+var ErrInvalidClientMsgID = errors.New("client_msg_id must be at most 64 characters")
+
 type messageUseCase struct {
 	messageRepo repository.MessageRepository
 }
@@ -16,14 +28,26 @@ func NewMessageUseCase(messageRepo repository.MessageRepository) MessageUseCase
 	}
 }
 
-// SendMessage implements MessageUseCase.
-func (m *messageUseCase) SaveMessage(ctx context.Context, senderID uint, receiverID uint, content string) error {
+// SaveMessage implements MessageUseCase.
+// false berarti clientMsgID sudah pernah disimpan; pesan yang dikembalikan adalah pesan lama.
+func (m *messageUseCase) SaveMessage(ctx context.Context, senderID uint, receiverID uint, content, clientMsgID string) (*entity.Message, bool, error) {
+	if len(clientMsgID) > maxClientMsgIDLength {
+		return nil, false, ErrInvalidClientMsgID
+	}
+
 	message := &entity.Message{
-		SenderID:   senderID,
-		ReceiverID: receiverID,
-		Content:    content,
+		SenderID:    senderID,
+		ReceiverID:  receiverID,
+		Content:     content,
+		ClientMsgID: clientMsgID,
+	}
+
+	created, err := m.messageRepo.SaveMessage(ctx, message)
+	if err != nil {
+		return nil, false, err
 	}
-	return m.messageRepo.SaveMessage(ctx, message)
+
+	return message, created, nil
 }
 
 // GetMessagesByUserID implements MessageUseCase.
@@ -36,12 +60,57 @@ func (m *messageUseCase) GetUndeliveredMessages(ctx context.Context, receiverID
 	return m.messageRepo.GetUndeliveredMessages(ctx, receiverID)
 }
 
+// MarkRead implements MessageUseCase.
+// Mengembalikan waktu baca, atau nil jika tidak ada pesan baru yang ditandai dibaca.
+func (m *messageUseCase) MarkRead(ctx context.Context, readerID, senderID, messageID uint) (*time.Time, error) {
+	now := time.Now()
+
+	marked, err := m.messageRepo.MarkReadUpTo(ctx, readerID, senderID, messageID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if marked == 0 {
+		return nil, nil
+	}
+
+	return &now, nil
+}
+
 // MarkMessageAsDelivered implements MessageUseCase.
 func (m *messageUseCase) MarkMessageAsDelivered(ctx context.Context, messageID uint) error {
 	return m.messageRepo.MarkMessageAsDelivered(ctx, messageID)
 }
 
-// GetLastMessage gets the last message sent between two users
-func (m *messageUseCase) GetLastMessage(ctx context.Context, senderID uint, receiverID uint) (*entity.Message, error) {
-	return m.messageRepo.GetLastMessage(ctx, senderID, receiverID)
+// ListConversations implements MessageUseCase.
+func (m *messageUseCase) ListConversations(ctx context.Context, userID, cursor uint, limit int) (*entity.ConversationPage, error) {
+	if limit <= 0 {
+		limit = defaultConversationLimit
+	}
+	if limit > maxConversationLimit {
+		limit = maxConversationLimit
+	}
+
+	// ambil satu percakapan lebih untuk mengetahui apakah masih ada halaman berikutnya
+	conversations, err := m.messageRepo.FindConversations(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.ConversationPage{Items: conversations}
+	if page.Items == nil {
+		page.Items = []*entity.Conversation{}
+	}
+
+	if len(conversations) > limit {
+		page.Items = conversations[:limit]
+		page.NextCursor = page.Items[limit-1].LastMessageID
+	}
+
+	return page, nil
+}
+
+// GetConversation implements MessageUseCase.
+func (m *messageUseCase) GetConversation(ctx context.Context, userID, peerID uint) (*entity.Conversation, error) {
+	return m.messageRepo.FindConversation(ctx, userID, peerID)
 }
@@ -0,0 +1,297 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/infrastructure/messaging"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"backend/pkg/phone"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	ErrInvalidPhoneNumber       = errors.New("invalid phone number")
+	ErrInvalidMessagingChannel  = errors.New("channel must be sms or whatsapp")
+	ErrTextMessageNotFound      = errors.New("text message not found")
+	ErrInvalidTextMessageStatus = errors.New("invalid text message status")
+)
+
+// smsMaxLength menjaga pesan SMS tetap satu segmen
+const smsMaxLength = 160
+
+type messagingUseCase struct {
+	gateway        messaging.MessagingGateway
+	txManager      repository.TxManager
+	messagingRepo  repository.MessagingRepository
+	preferenceRepo repository.NotificationPreferenceRepository
+	userRepo       repository.UserRepository
+	outboxRepo     repository.OutboxRepository
+}
+
+func NewMessagingUseCase(
+	gateway messaging.MessagingGateway,
+	txManager repository.TxManager,
+	messagingRepo repository.MessagingRepository,
+	preferenceRepo repository.NotificationPreferenceRepository,
+	userRepo repository.UserRepository,
+	outboxRepo repository.OutboxRepository,
+) MessagingUseCase {
+	return &messagingUseCase{
+		gateway:        gateway,
+		txManager:      txManager,
+		messagingRepo:  messagingRepo,
+		preferenceRepo: preferenceRepo,
+		userRepo:       userRepo,
+		outboxRepo:     outboxRepo,
+	}
+}
+
+// GetPreference implements MessagingUseCase.
+func (m *messagingUseCase) GetPreference(ctx context.Context, userID uint) (*entity.MessagingPreference, error) {
+	preference, err := m.messagingRepo.FindPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if preference == nil {
+		return &entity.MessagingPreference{UserID: userID, Channel: string(messaging.ChannelSMS)}, nil
+	}
+
+	return preference, nil
+}
+
+// OptIn implements MessagingUseCase.
+// User harus punya nomor telepon yang valid agar pesan bisa dikirim.
+func (m *messagingUseCase) OptIn(ctx context.Context, userID uint, channel string) (*entity.MessagingPreference, error) {
+	if channel == "" {
+		channel = string(messaging.ChannelSMS)
+	}
+
+	if !messaging.Channel(channel).IsValid() {
+		return nil, ErrInvalidMessagingChannel
+	}
+
+	user, err := m.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if _, err := phone.NormalizeE164(user.PhoneNumber, phone.DefaultCountryCode); err != nil {
+		return nil, ErrInvalidPhoneNumber
+	}
+
+	preference, err := m.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preference.OptedIn = true
+	preference.Channel = channel
+	preference.OptedInAt = &now
+
+	if err := m.messagingRepo.UpsertPreference(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	return preference, nil
+}
+
+// OptOut implements MessagingUseCase.
+func (m *messagingUseCase) OptOut(ctx context.Context, userID uint) (*entity.MessagingPreference, error) {
+	preference, err := m.GetPreference(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	preference.OptedIn = false
+	preference.OptedOutAt = &now
+
+	if err := m.messagingRepo.UpsertPreference(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	return preference, nil
+}
+
+// SendUrgentFallback implements MessagingUseCase.
+// Dipanggil untuk pendonor yang tidak bisa dijangkau push pada request urgent. Hanya pendonor yang
+// opt-in dan masih menerima kategori urgent_request yang akan dihubungi. Pesan dicatat satu per
+// pendonor per request sebelum dikirim, lalu dikirim ke gateway lewat outbox. Mengembalikan user
+// yang pesannya masuk antrean.
+func (m *messagingUseCase) SendUrgentFallback(ctx context.Context, userIDs []uint, bloodRequestID uint, title, body string) []uint {
+	recipients, err := m.messagingRepo.FindOptedInRecipients(ctx, userIDs)
+	if err != nil {
+		log.Printf("Failed to find sms recipients for blood request %d: %v", bloodRequestID, err)
+		return nil
+	}
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	candidates := make([]uint, 0, len(recipients))
+	for _, recipient := range recipients {
+		candidates = append(candidates, recipient.UserID)
+	}
+
+	excluded, err := m.excludedRecipients(ctx, candidates)
+	if err != nil {
+		log.Printf("Failed to check sms recipients for blood request %d: %v", bloodRequestID, err)
+		return nil
+	}
+
+	text := title + ": " + body
+
+	var queued []uint
+	for _, recipient := range recipients {
+		if excluded[recipient.UserID] {
+			continue
+		}
+
+		if m.queueText(ctx, recipient, bloodRequestID, text) {
+			queued = append(queued, recipient.UserID)
+		}
+	}
+
+	return queued
+}
+
+// FindFallbackDonors implements MessagingUseCase.
+func (m *messagingUseCase) FindFallbackDonors(ctx context.Context, groups []bloodcompat.Group, afterUserID uint, limit int) ([]uint, error) {
+	return m.messagingRepo.FindOptedInDonors(ctx, groups, afterUserID, limit)
+}
+
+// excludedRecipients adalah user yang mematikan notifikasi request urgent
+func (m *messagingUseCase) excludedRecipients(ctx context.Context, userIDs []uint) (map[uint]bool, error) {
+	excluded := map[uint]bool{}
+
+	preferences, err := m.preferenceRepo.FindByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, preference := range preferences {
+		if !preference.Allows(entity.CategoryUrgentRequest) {
+			excluded[preference.UserID] = true
+		}
+	}
+
+	return excluded, nil
+}
+
+// queueText mencatat pesan dan memasukkannya ke outbox dalam satu transaksi. Mengembalikan false
+// jika pendonor sudah pernah dikirimi pesan untuk request yang sama.
+func (m *messagingUseCase) queueText(ctx context.Context, recipient *entity.MessagingRecipient, bloodRequestID uint, text string) bool {
+	number, err := phone.NormalizeE164(recipient.PhoneNumber, phone.DefaultCountryCode)
+	if err != nil {
+		log.Printf("Skipping sms for user %d: %v", recipient.UserID, err)
+		return false
+	}
+
+	if messaging.Channel(recipient.Channel) == messaging.ChannelSMS {
+		text = truncateRunes(text, smsMaxLength)
+	}
+
+	message := &entity.TextMessage{
+		UserID:         recipient.UserID,
+		BloodRequestID: &bloodRequestID,
+		Channel:        recipient.Channel,
+		ToNumber:       number,
+		Body:           text,
+		Status:         entity.TextMessageQueued,
+	}
+
+	created := false
+	err = m.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		ok, err := m.messagingRepo.CreateMessage(ctx, message)
+		if err != nil || !ok {
+			return err
+		}
+		created = true
+
+		_, err = enqueueOutbox(ctx, m.outboxRepo, entity.OutboxTextMessage, entity.TextMessagePayload{TextMessageID: message.ID})
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to queue sms for user %d: %v", recipient.UserID, err)
+		return false
+	}
+
+	return created
+}
+
+// DeliverText implements MessagingUseCase.
+// Dipanggil worker outbox. Pesan yang sudah terkirim tidak dikirim ulang; kegagalan gateway
+// dikembalikan agar outbox mencoba lagi, kecuali nomor tujuan ditolak provider.
+func (m *messagingUseCase) DeliverText(ctx context.Context, id uint) error {
+	message, err := m.messagingRepo.FindMessageById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if message == nil {
+		return ErrTextMessageNotFound
+	}
+
+	if message.Status != entity.TextMessageQueued {
+		return nil
+	}
+
+	channel := messaging.Channel(message.Channel)
+	providerID, sendErr := m.gateway.Send(ctx, messaging.Message{To: message.ToNumber, Channel: channel, Body: message.Body})
+
+	status, errMessage := entity.TextMessageSent, ""
+	if sendErr != nil {
+		// pesan tetap queued selama masih bisa dicoba ulang
+		status, errMessage = entity.TextMessageQueued, sendErr.Error()
+		if errors.Is(sendErr, messaging.ErrInvalidRecipient) {
+			status = entity.TextMessageFailed
+		}
+	}
+
+	if err := m.messagingRepo.UpdateMessageStatus(ctx, message.ID, status, providerID, errMessage); err != nil {
+		log.Printf("Failed to update status of text message %d: %v", message.ID, err)
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("failed to send %s to user %d: %w", channel, message.UserID, sendErr)
+	}
+
+	return nil
+}
+
+// UpdateDeliveryStatus implements MessagingUseCase.
+func (m *messagingUseCase) UpdateDeliveryStatus(ctx context.Context, providerMessageID string, status entity.TextMessageStatus, errMessage string) error {
+	if providerMessageID == "" || !status.IsValid() {
+		return ErrInvalidTextMessageStatus
+	}
+
+	updated, err := m.messagingRepo.UpdateStatusByProviderID(ctx, providerMessageID, status, errMessage)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		return ErrTextMessageNotFound
+	}
+
+	return nil
+}
+
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
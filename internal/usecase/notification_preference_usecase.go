@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidNotificationPreference = errors.New("invalid notification preference")
+
+// maxPushPerDayLimit adalah batas atas max_per_day yang boleh diisi user
+const maxPushPerDayLimit = 100
+
+type notificationPreferenceUseCase struct {
+	preferenceRepo repository.NotificationPreferenceRepository
+}
+
+func NewNotificationPreferenceUseCase(preferenceRepo repository.NotificationPreferenceRepository) NotificationPreferenceUseCase {
+	return &notificationPreferenceUseCase{
+		preferenceRepo: preferenceRepo,
+	}
+}
+
+// Get implements NotificationPreferenceUseCase.
+func (n *notificationPreferenceUseCase) Get(ctx context.Context, userID uint) (*entity.NotificationPreference, error) {
+	preference, err := n.preferenceRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if preference == nil {
+		return entity.DefaultNotificationPreference(userID), nil
+	}
+
+	return preference, nil
+}
+
+// Update implements NotificationPreferenceUseCase.
+func (n *notificationPreferenceUseCase) Update(ctx context.Context, userID uint, preference *entity.NotificationPreference) (*entity.NotificationPreference, error) {
+	if preference.MaxPerDay < 0 || preference.MaxPerDay > maxPushPerDayLimit {
+		return nil, fmt.Errorf("%w: max_per_day must be between 0 and %d", ErrInvalidNotificationPreference, maxPushPerDayLimit)
+	}
+
+	// quiet hours harus diisi keduanya atau dikosongkan keduanya
+	if (preference.QuietHoursStart == "") != (preference.QuietHoursEnd == "") {
+		return nil, fmt.Errorf("%w: quiet_hours_start and quiet_hours_end must be set together", ErrInvalidNotificationPreference)
+	}
+
+	for _, clock := range []string{preference.QuietHoursStart, preference.QuietHoursEnd} {
+		if clock == "" {
+			continue
+		}
+		if _, err := entity.ParseClock(clock); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidNotificationPreference, err)
+		}
+	}
+
+	if preference.Timezone == "" {
+		preference.Timezone = entity.DefaultTimezone
+	}
+
+	if _, err := time.LoadLocation(preference.Timezone); err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidNotificationPreference, preference.Timezone)
+	}
+
+	preference.UserID = userID
+	if err := n.preferenceRepo.Upsert(ctx, preference); err != nil {
+		return nil, err
+	}
+
+	return preference, nil
+}
+
+// Filter implements NotificationPreferenceUseCase.
+// Filter membagi userIDs menjadi user yang boleh menerima push sekarang dan user yang push-nya
+// ditahan karena quiet hours atau batas harian. User yang mematikan kategori tidak masuk
+// keduanya. Kategori urgent_request boleh menembus quiet hours, tetapi tetap dihitung
+// ke batas harian. Jatah harian user yang diizinkan langsung dipakai; kembalikan lewat
+// Release jika push-nya gagal terkirim.
+func (n *notificationPreferenceUseCase) Filter(ctx context.Context, userIDs []uint, category entity.NotificationCategory, now time.Time) ([]uint, []uint, error) {
+	stored, err := n.preferenceRepo.FindByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	preferences := make(map[uint]*entity.NotificationPreference, len(stored))
+	for _, preference := range stored {
+		preferences[preference.UserID] = preference
+	}
+
+	var allowed, suppressed []uint
+	seen := map[uint]bool{}
+	for _, userID := range userIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		preference, ok := preferences[userID]
+		if !ok {
+			preference = entity.DefaultNotificationPreference(userID)
+		}
+
+		if !preference.Allows(category) {
+			continue
+		}
+
+		if category != entity.CategoryUrgentRequest && preference.InQuietHours(now) {
+			suppressed = append(suppressed, userID)
+			continue
+		}
+
+		if preference.MaxPerDay > 0 {
+			reserved, err := n.preferenceRepo.ReservePush(ctx, userID, now.In(preference.Location()), preference.MaxPerDay)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if !reserved {
+				suppressed = append(suppressed, userID)
+				continue
+			}
+		}
+
+		allowed = append(allowed, userID)
+	}
+
+	return allowed, suppressed, nil
+}
+
+// Release implements NotificationPreferenceUseCase.
+// Release mengembalikan jatah harian yang dipakai Filter untuk user yang push-nya gagal terkirim.
+func (n *notificationPreferenceUseCase) Release(ctx context.Context, userIDs []uint, now time.Time) error {
+	stored, err := n.preferenceRepo.FindByUserIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, preference := range stored {
+		if preference.MaxPerDay <= 0 {
+			continue
+		}
+
+		if err := n.preferenceRepo.ReleasePush(ctx, preference.UserID, now.In(preference.Location())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+const (
+	defaultNotificationLimit = 20
+	maxNotificationLimit     = 50
+)
+
+type notificationUseCase struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationUseCase(notificationRepo repository.NotificationRepository) NotificationUseCase {
+	return &notificationUseCase{
+		notificationRepo: notificationRepo,
+	}
+}
+
+// List implements NotificationUseCase.
+func (n *notificationUseCase) List(ctx context.Context, userID, cursor uint, limit int) (*entity.NotificationPage, error) {
+	if limit <= 0 {
+		limit = defaultNotificationLimit
+	}
+	if limit > maxNotificationLimit {
+		limit = maxNotificationLimit
+	}
+
+	notifications, err := n.notificationRepo.FindByUserID(ctx, userID, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.NotificationPage{Items: notifications}
+	if page.Items == nil {
+		page.Items = []*entity.Notification{}
+	}
+
+	// halaman penuh berarti mungkin masih ada notifikasi yang lebih lama
+	if len(notifications) == limit {
+		page.NextCursor = notifications[len(notifications)-1].ID
+	}
+
+	return page, nil
+}
+
+// MarkRead implements NotificationUseCase.
+func (n *notificationUseCase) MarkRead(ctx context.Context, userID, id uint) error {
+	found, err := n.notificationRepo.MarkRead(ctx, userID, id, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// MarkAllRead implements NotificationUseCase.
+func (n *notificationUseCase) MarkAllRead(ctx context.Context, userID uint) error {
+	return n.notificationRepo.MarkAllRead(ctx, userID, time.Now())
+}
+
+// UnreadCount implements NotificationUseCase.
+func (n *notificationUseCase) UnreadCount(ctx context.Context, userID uint) (int, error) {
+	return n.notificationRepo.CountUnread(ctx, userID)
+}
@@ -0,0 +1,344 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/infrastructure/messaging"
+	"backend/internal/infrastructure/push"
+	"backend/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+var (
+	ErrOutboxNotFound      = errors.New("outbox message not found")
+	ErrOutboxNotReplayable = errors.New("only dead outbox messages can be replayed")
+	ErrInvalidOutboxStatus = errors.New("invalid outbox status")
+	errUnknownOutboxKind   = errors.New("unknown outbox kind")
+)
+
+const (
+	outboxMaxAttempts = 6
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	// outboxLease adalah batas waktu satu pengiriman sebelum message boleh diambil ulang.
+	// Lease diperpanjang sebelum setiap message dikirim sehingga berlaku per message, bukan per batch.
+	outboxLease     = 5 * time.Minute
+	outboxBatchSize = 50
+	// outboxSuppressedDelay adalah jeda sebelum push yang ditahan preferensi user dicoba lagi
+	outboxSuppressedDelay = 30 * time.Minute
+
+	defaultOutboxListLimit = 50
+	maxOutboxListLimit     = 200
+)
+
+// enqueueOutbox menulis message ke outbox. Panggil di dalam TxManager.WithinTx agar
+// notifikasi hanya tercatat jika perubahan data yang memicunya ikut tersimpan.
+func enqueueOutbox(ctx context.Context, outboxRepo repository.OutboxRepository, kind entity.OutboxKind, payload any) (*entity.OutboxMessage, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &entity.OutboxMessage{
+		Kind:    kind,
+		Payload: data,
+	}
+
+	if err := outboxRepo.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// outboxBackoff menggandakan jeda setiap percobaan: 30s, 1m, 2m, ... maksimal 1 jam
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
+
+// isPermanentOutboxError menandakan message tidak akan berhasil walaupun dicoba ulang
+func isPermanentOutboxError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	return errors.Is(err, errUnknownOutboxKind) ||
+		errors.Is(err, ErrBloodRequestNotFound) ||
+		errors.Is(err, ErrBloodRequestClosed) ||
+		errors.Is(err, ErrInvalidBloodRequest) ||
+		errors.Is(err, ErrNotPencari) ||
+		errors.Is(err, ErrBloodRequestForbidden) ||
+		errors.Is(err, ErrBroadcastDisabled) ||
+		errors.Is(err, ErrNoDeviceToken) ||
+		errors.Is(err, push.ErrInvalidToken) ||
+		errors.Is(err, ErrTextMessageNotFound) ||
+		errors.Is(err, ErrEscalationNotFound) ||
+		errors.Is(err, messaging.ErrInvalidRecipient) ||
+		errors.As(err, &syntaxErr) ||
+		errors.As(err, &typeErr)
+}
+
+type outboxUseCase struct {
+	outboxRepo        repository.OutboxRepository
+	userRepo          repository.UserRepository
+	stockRepo         repository.FacilityStockRepository
+	flagRepo          repository.FeatureFlagRepository
+	fcmUseCase        FCMUseCase
+	messagingUseCase  MessagingUseCase
+	escalationUseCase EscalationUseCase
+}
+
+func NewOutboxUseCase(
+	outboxRepo repository.OutboxRepository,
+	userRepo repository.UserRepository,
+	stockRepo repository.FacilityStockRepository,
+	flagRepo repository.FeatureFlagRepository,
+	fcmUseCase FCMUseCase,
+	messagingUseCase MessagingUseCase,
+	escalationUseCase EscalationUseCase,
+) OutboxUseCase {
+	return &outboxUseCase{
+		outboxRepo:        outboxRepo,
+		userRepo:          userRepo,
+		stockRepo:         stockRepo,
+		flagRepo:          flagRepo,
+		fcmUseCase:        fcmUseCase,
+		messagingUseCase:  messagingUseCase,
+		escalationUseCase: escalationUseCase,
+	}
+}
+
+// DeliverDue implements OutboxUseCase.
+func (o *outboxUseCase) DeliverDue(ctx context.Context) (int, error) {
+	now := time.Now()
+	messages, err := o.outboxRepo.ClaimDue(ctx, now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, message := range messages {
+		renewed, err := o.outboxRepo.RenewLease(ctx, message.ID, message.Attempts, time.Now().Add(outboxLease), time.Now())
+		if err != nil {
+			log.Printf("Failed to renew lease of outbox message %d: %v", message.ID, err)
+			continue
+		}
+
+		// lease habis saat batch sebelumnya dikirim dan message sudah diambil worker lain
+		if !renewed {
+			continue
+		}
+
+		err = o.deliver(ctx, message)
+		at := time.Now()
+
+		if err == nil {
+			if err := o.outboxRepo.MarkSent(ctx, message.ID, at); err != nil {
+				log.Printf("Failed to mark outbox message %d as sent: %v", message.ID, err)
+			}
+			delivered++
+			continue
+		}
+
+		// push yang ditahan quiet hours atau batas harian bukan kegagalan, dicoba lagi nanti
+		if errors.Is(err, ErrPushSuppressed) {
+			if err := o.outboxRepo.Defer(ctx, message.ID, at.Add(outboxSuppressedDelay), err.Error(), at); err != nil {
+				log.Printf("Failed to defer outbox message %d: %v", message.ID, err)
+			}
+			continue
+		}
+
+		status := entity.OutboxPending
+		if message.Attempts >= outboxMaxAttempts || isPermanentOutboxError(err) {
+			status = entity.OutboxDead
+		}
+
+		log.Printf("Failed to deliver outbox message %d (%s, attempt %d, %s): %v", message.ID, message.Kind, message.Attempts, status, err)
+
+		if err := o.outboxRepo.MarkFailed(ctx, message.ID, status, at.Add(outboxBackoff(message.Attempts)), err.Error(), at); err != nil {
+			log.Printf("Failed to reschedule outbox message %d: %v", message.ID, err)
+		}
+	}
+
+	return delivered, nil
+}
+
+func (o *outboxUseCase) deliver(ctx context.Context, message *entity.OutboxMessage) error {
+	switch message.Kind {
+	case entity.OutboxPushUser:
+		var payload entity.PushUserPayload
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			return err
+		}
+		return o.fcmUseCase.SendToUser(ctx, payload.UserID, payload.Title, payload.Body, payload.Data)
+
+	case entity.OutboxBroadcast:
+		var payload entity.BroadcastPayload
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			return err
+		}
+
+		// broadcast yang sudah antre ikut dihentikan kill switch; admin bisa replay setelahnya
+		disabled, err := broadcastsDisabled(ctx, o.flagRepo)
+		if err != nil {
+			return err
+		}
+
+		if disabled {
+			return ErrBroadcastDisabled
+		}
+
+		reached, err := o.reachedRecipients(ctx, message.ID)
+		if err != nil {
+			return err
+		}
+
+		notified, err := o.fcmUseCase.SendFCMV1(ctx, payload.UserID, payload.BloodRequestID, payload.Title, payload.Body, reached)
+		o.recordRecipients(ctx, message.ID, notified)
+		return err
+
+	case entity.OutboxDonorsNear:
+		var payload entity.DonorsNearPayload
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			return err
+		}
+
+		reached, err := o.reachedRecipients(ctx, message.ID)
+		if err != nil {
+			return err
+		}
+
+		notified, err := o.fcmUseCase.NotifyDonorsNear(ctx, payload.Latitude, payload.Longitude, payload.RadiusKm, payload.Groups, payload.Title, payload.Body, payload.Data, reached)
+		o.recordRecipients(ctx, message.ID, notified)
+
+		if payload.ShortageID != 0 && (err == nil || len(notified) > 0) {
+			if err := o.stockRepo.UpdateShortageNotified(ctx, payload.ShortageID, len(reached)+len(notified)); err != nil {
+				log.Printf("Failed to record notified count for shortage %d: %v", payload.ShortageID, err)
+			}
+		}
+		return err
+
+	case entity.OutboxTextMessage:
+		var payload entity.TextMessagePayload
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			return err
+		}
+		return o.messagingUseCase.DeliverText(ctx, payload.TextMessageID)
+
+	case entity.OutboxEscalation:
+		var payload entity.EscalationPayload
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			return err
+		}
+		return o.escalationUseCase.DeliverEscalation(ctx, payload.EscalationID)
+
+	default:
+		return fmt.Errorf("%w: %s", errUnknownOutboxKind, message.Kind)
+	}
+}
+
+// reachedRecipients mengambil user yang sudah dijangkau percobaan sebelumnya dari message multi-penerima
+func (o *outboxUseCase) reachedRecipients(ctx context.Context, id uint) (map[uint]bool, error) {
+	userIDs, err := o.outboxRepo.FindRecipients(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	reached := make(map[uint]bool, len(userIDs))
+	for _, userID := range userIDs {
+		reached[userID] = true
+	}
+	return reached, nil
+}
+
+// recordRecipients mencatat user yang dijangkau agar tidak dikirimi ulang saat message dicoba lagi
+func (o *outboxUseCase) recordRecipients(ctx context.Context, id uint, userIDs []uint) {
+	if err := o.outboxRepo.AddRecipients(ctx, id, userIDs, time.Now()); err != nil {
+		log.Printf("Failed to record recipients of outbox message %d: %v", id, err)
+	}
+}
+
+// RunOutboxWorker implements OutboxUseCase.
+func (o *outboxUseCase) RunOutboxWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			delivered, err := o.DeliverDue(ctx)
+			if err != nil {
+				log.Printf("Failed to deliver outbox messages: %v", err)
+				continue
+			}
+
+			if delivered > 0 {
+				log.Printf("Delivered %d outbox messages", delivered)
+			}
+		}
+	}
+}
+
+// GetMessages implements OutboxUseCase.
+func (o *outboxUseCase) GetMessages(ctx context.Context, adminID uint, status entity.OutboxStatus, limit int) ([]*entity.OutboxMessage, error) {
+	if err := requireAdmin(ctx, o.userRepo, adminID); err != nil {
+		return nil, err
+	}
+
+	if status == "" {
+		status = entity.OutboxDead
+	}
+
+	if !status.IsValid() {
+		return nil, ErrInvalidOutboxStatus
+	}
+
+	if limit <= 0 {
+		limit = defaultOutboxListLimit
+	}
+	if limit > maxOutboxListLimit {
+		limit = maxOutboxListLimit
+	}
+
+	return o.outboxRepo.FindByStatus(ctx, status, limit)
+}
+
+// Replay implements OutboxUseCase.
+func (o *outboxUseCase) Replay(ctx context.Context, adminID, id uint) (*entity.OutboxMessage, error) {
+	if err := requireAdmin(ctx, o.userRepo, adminID); err != nil {
+		return nil, err
+	}
+
+	message, err := o.outboxRepo.FindById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if message == nil {
+		return nil, ErrOutboxNotFound
+	}
+
+	replayed, err := o.outboxRepo.Replay(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !replayed {
+		return nil, ErrOutboxNotReplayable
+	}
+
+	return o.outboxRepo.FindById(ctx, id)
+}
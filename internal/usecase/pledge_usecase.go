@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrPledgeNotFound      = errors.New("pledge not found")
+	ErrPledgeExists        = errors.New("donor already pledged to this blood request")
+	ErrInvalidPledgeStatus = errors.New("invalid pledge status transition")
+	ErrNotPendonor         = errors.New("user role must be pendonor")
+	ErrBloodIncompatible   = errors.New("donor blood type is not compatible with this request")
+	ErrOwnBloodRequest     = errors.New("cannot pledge to your own blood request")
+	ErrPledgeLimitReached  = errors.New("accepted pledges already cover the requested total")
+)
+
+var pledgeTransitions = map[entity.PledgeStatus][]entity.PledgeStatus{
+	entity.PledgePending:  {entity.PledgeAccepted, entity.PledgeDeclined},
+	entity.PledgeAccepted: {entity.PledgeDeclined, entity.PledgeNoShow, entity.PledgeDonated},
+}
+
+type pledgeUseCase struct {
+	txManager           repository.TxManager
+	pledgeRepo          repository.PledgeRepository
+	bloodRequestRepo    repository.BloodRequestRepository
+	userRepo            repository.UserRepository
+	messageRepo         repository.MessageRepository
+	bloodRequestUseCase BloodRequestUseCase
+}
+
+func NewPledgeUseCase(
+	txManager repository.TxManager,
+	pledgeRepo repository.PledgeRepository,
+	bloodRequestRepo repository.BloodRequestRepository,
+	userRepo repository.UserRepository,
+	messageRepo repository.MessageRepository,
+	bloodRequestUseCase BloodRequestUseCase,
+) PledgeUseCase {
+	return &pledgeUseCase{
+		txManager:           txManager,
+		pledgeRepo:          pledgeRepo,
+		bloodRequestRepo:    bloodRequestRepo,
+		userRepo:            userRepo,
+		messageRepo:         messageRepo,
+		bloodRequestUseCase: bloodRequestUseCase,
+	}
+}
+
+// Pledge implements PledgeUseCase.
+func (p *pledgeUseCase) Pledge(ctx context.Context, donorID, bloodRequestID uint) (*entity.Pledge, error) {
+	donor, err := p.userRepo.FindById(ctx, donorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if donor == nil || donor.Role != "pendonor" {
+		return nil, ErrNotPendonor
+	}
+
+	request, err := p.bloodRequestRepo.FindById(ctx, bloodRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, ErrBloodRequestNotFound
+	}
+
+	if request.UserID == donorID {
+		return nil, ErrOwnBloodRequest
+	}
+
+	if !request.Status.IsActive() {
+		return nil, ErrBloodRequestClosed
+	}
+
+	donorGroup := bloodcompat.Group{BloodType: donor.BloodType, Rhesus: donor.Rhesus}
+	recipientGroup := bloodcompat.Group{BloodType: request.BloodType, Rhesus: request.Rhesus}
+	if !bloodcompat.CanDonate(donorGroup, recipientGroup) {
+		return nil, ErrBloodIncompatible
+	}
+
+	pledge := &entity.Pledge{
+		BloodRequestID: bloodRequestID,
+		DonorID:        donorID,
+		Status:         entity.PledgePending,
+	}
+
+	created, err := p.pledgeRepo.Create(ctx, pledge)
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		return nil, ErrPledgeExists
+	}
+
+	return pledge, nil
+}
+
+// Accept implements PledgeUseCase.
+func (p *pledgeUseCase) Accept(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, error) {
+	pledge, request, err := p.findForRequester(ctx, requesterID, pledgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// status pledge, status request dan pesan pembuka disimpan bersama
+	err = p.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// kunci request agar accept bersamaan tidak melewati total
+		request, err = p.bloodRequestRepo.FindByIdForUpdate(ctx, request.ID)
+		if err != nil {
+			return err
+		}
+
+		if request == nil {
+			return ErrBloodRequestNotFound
+		}
+
+		if !request.Status.IsActive() {
+			return ErrBloodRequestClosed
+		}
+
+		accepted, err := p.pledgeRepo.CountAccepted(ctx, request.ID)
+		if err != nil {
+			return err
+		}
+
+		if accepted >= request.Total {
+			return ErrPledgeLimitReached
+		}
+
+		pledge, err = p.transition(ctx, pledge, entity.PledgeAccepted)
+		if err != nil {
+			return err
+		}
+
+		if err := p.syncRequestStatus(ctx, request); err != nil {
+			return err
+		}
+
+		// Pesan pembuka menjadi pasangan chat antara pencari dan pendonor,
+		// dikirim lewat WebSocket sebagai pesan yang belum terkirim
+		opening := &entity.Message{
+			SenderID:    requesterID,
+			ReceiverID:  pledge.DonorID,
+			Content:     fmt.Sprintf("Terima kasih sudah bersedia membantu permintaan darah untuk %s. Yuk lanjutkan koordinasinya lewat chat ini.", request.SearchName),
+			ClientMsgID: fmt.Sprintf("pledge-%d", pledge.ID),
+		}
+
+		_, err = p.messageRepo.SaveMessage(ctx, opening)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pledge, nil
+}
+
+// Decline implements PledgeUseCase.
+func (p *pledgeUseCase) Decline(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, error) {
+	return p.release(ctx, requesterID, pledgeID, entity.PledgeDeclined)
+}
+
+// MarkNoShow implements PledgeUseCase.
+func (p *pledgeUseCase) MarkNoShow(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, error) {
+	return p.release(ctx, requesterID, pledgeID, entity.PledgeNoShow)
+}
+
+// release mengubah pledge ke status yang tidak lagi dihitung sebagai diterima lalu menyesuaikan
+// status request dalam satu transaksi
+func (p *pledgeUseCase) release(ctx context.Context, requesterID, pledgeID uint, to entity.PledgeStatus) (*entity.Pledge, error) {
+	pledge, request, err := p.findForRequester(ctx, requesterID, pledgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		// kunci request seperti Accept agar jumlah pledge yang diterima tidak berubah bersamaan
+		request, err = p.bloodRequestRepo.FindByIdForUpdate(ctx, request.ID)
+		if err != nil {
+			return err
+		}
+
+		if request == nil {
+			return ErrBloodRequestNotFound
+		}
+
+		pledge, err = p.transition(ctx, pledge, to)
+		if err != nil {
+			return err
+		}
+
+		return p.syncRequestStatus(ctx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pledge, nil
+}
+
+// GetByBloodRequestID implements PledgeUseCase.
+func (p *pledgeUseCase) GetByBloodRequestID(ctx context.Context, requesterID, bloodRequestID uint) ([]*entity.Pledge, error) {
+	request, err := p.bloodRequestRepo.FindById(ctx, bloodRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request == nil {
+		return nil, ErrBloodRequestNotFound
+	}
+
+	if request.UserID != requesterID {
+		return nil, ErrBloodRequestForbidden
+	}
+
+	return p.pledgeRepo.FindByBloodRequestID(ctx, bloodRequestID)
+}
+
+// GetByDonorID implements PledgeUseCase.
+func (p *pledgeUseCase) GetByDonorID(ctx context.Context, donorID uint) ([]*entity.Pledge, error) {
+	return p.pledgeRepo.FindByDonorID(ctx, donorID)
+}
+
+// findForRequester mengambil pledge beserta blood request-nya dan memastikan
+// hanya pemilik request yang bisa mengubah status pledge
+func (p *pledgeUseCase) findForRequester(ctx context.Context, requesterID, pledgeID uint) (*entity.Pledge, *entity.BloodRequest, error) {
+	pledge, err := p.pledgeRepo.FindById(ctx, pledgeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if pledge == nil {
+		return nil, nil, ErrPledgeNotFound
+	}
+
+	request, err := p.bloodRequestRepo.FindById(ctx, pledge.BloodRequestID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if request == nil {
+		return nil, nil, ErrBloodRequestNotFound
+	}
+
+	if request.UserID != requesterID {
+		return nil, nil, ErrBloodRequestForbidden
+	}
+
+	return pledge, request, nil
+}
+
+func (p *pledgeUseCase) transition(ctx context.Context, pledge *entity.Pledge, to entity.PledgeStatus) (*entity.Pledge, error) {
+	allowed := false
+	for _, status := range pledgeTransitions[pledge.Status] {
+		if status == to {
+			allowed = true
+			break
+		}
+	}
+
+	if !allowed {
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidPledgeStatus, pledge.Status, to)
+	}
+
+	updated, err := p.pledgeRepo.UpdateStatus(ctx, pledge.ID, pledge.Status, to, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !updated {
+		return nil, fmt.Errorf("%w: status changed concurrently", ErrInvalidPledgeStatus)
+	}
+
+	return p.pledgeRepo.FindById(ctx, pledge.ID)
+}
+
+// syncRequestStatus menyesuaikan status blood request dengan jumlah pledge yang diterima
+func (p *pledgeUseCase) syncRequestStatus(ctx context.Context, request *entity.BloodRequest) error {
+	accepted, err := p.pledgeRepo.CountAccepted(ctx, request.ID)
+	if err != nil {
+		return err
+	}
+
+	var to entity.BloodRequestStatus
+	switch {
+	case accepted > 0 && request.Status == entity.BloodRequestOpen:
+		to = entity.BloodRequestPartiallyPledged
+	case accepted == 0 && request.Status == entity.BloodRequestPartiallyPledged:
+		to = entity.BloodRequestOpen
+	default:
+		return nil
+	}
+
+	_, err = p.bloodRequestUseCase.Transition(ctx, request.ID, to)
+	if errors.Is(err, ErrBloodRequestClosed) {
+		return nil
+	}
+
+	return err
+}
@@ -4,8 +4,10 @@ import (
 	"backend/internal/entity"
 	"backend/internal/infrastructure/storage"
 	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
 	"context"
 	"fmt"
+	"log"
 	"mime/multipart"
 	"path/filepath"
 	"strings"
@@ -13,16 +15,18 @@ import (
 )
 
 type profileUseCase struct {
-	userRepo    repository.UserRepository
-	fileStorage storage.FileStorage
+	userRepo            repository.UserRepository
+	fileStorage         storage.FileStorage
+	subscriptionUseCase TopicSubscriptionUseCase
 }
 
 func NewProfileUseCase(
-	userRepo repository.UserRepository, fileStorage storage.FileStorage,
+	userRepo repository.UserRepository, fileStorage storage.FileStorage, subscriptionUseCase TopicSubscriptionUseCase,
 ) ProfileUseCase {
 	return &profileUseCase{
-		userRepo:    userRepo,
-		fileStorage: fileStorage,
+		userRepo:            userRepo,
+		fileStorage:         fileStorage,
+		subscriptionUseCase: subscriptionUseCase,
 	}
 }
 
@@ -79,3 +83,28 @@ func (p *profileUseCase) UpdateProfilePhoto(
 func (p *profileUseCase) GetUserProfile(ctx context.Context, userID uint) (*entity.User, error) {
 	return p.userRepo.FindById(ctx, userID)
 }
+
+func (p *profileUseCase) UpdateLocation(ctx context.Context, userID uint, latitude, longitude float64) error {
+	if latitude < -90 || latitude > 90 || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("invalid coordinates")
+	}
+
+	return p.userRepo.UpdateLocation(ctx, userID, latitude, longitude)
+}
+
+func (p *profileUseCase) UpdateBloodType(ctx context.Context, userID uint, bloodType, rhesus string) error {
+	if !bloodcompat.IsValid(bloodcompat.Group{BloodType: bloodType, Rhesus: rhesus}) {
+		return fmt.Errorf("invalid blood type or rhesus")
+	}
+
+	if err := p.userRepo.UpdateBloodType(ctx, userID, bloodType, rhesus); err != nil {
+		return err
+	}
+
+	// golongan darah berubah, topic broadcast yang diikuti juga harus ikut berubah
+	if err := p.subscriptionUseCase.Sync(ctx, userID); err != nil {
+		log.Printf("Failed to sync topic subscriptions for user %d: %v", userID, err)
+	}
+
+	return nil
+}
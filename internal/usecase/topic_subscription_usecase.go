@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"backend/internal/entity"
+	"backend/internal/repository"
+	"backend/pkg/bloodcompat"
+	"context"
+	"errors"
+	"log"
+)
+
+type topicSubscriptionUseCase struct {
+	subscriptionRepo repository.TopicSubscriptionRepository
+	deviceTokenRepo  repository.DeviceTokenRepository
+	userRepo         repository.UserRepository
+	fcmUseCase       FCMUseCase
+}
+
+func NewTopicSubscriptionUseCase(
+	subscriptionRepo repository.TopicSubscriptionRepository,
+	deviceTokenRepo repository.DeviceTokenRepository,
+	userRepo repository.UserRepository,
+	fcmUseCase FCMUseCase,
+) TopicSubscriptionUseCase {
+	return &topicSubscriptionUseCase{
+		subscriptionRepo: subscriptionRepo,
+		deviceTokenRepo:  deviceTokenRepo,
+		userRepo:         userRepo,
+		fcmUseCase:       fcmUseCase,
+	}
+}
+
+// subscriptionKey adalah pasangan token perangkat dan topic
+type subscriptionKey struct {
+	token string
+	topic string
+}
+
+// desiredTopics menghitung topic yang seharusnya diikuti user. Hanya pendonor dengan
+// golongan darah lengkap yang menerima broadcast kebutuhan darah.
+func desiredTopics(user *entity.User) []string {
+	group := bloodcompat.Group{BloodType: user.BloodType, Rhesus: user.Rhesus}
+	if user.Role != "pendonor" || !bloodcompat.IsValid(group) {
+		return nil
+	}
+
+	return bloodcompat.TopicsForDonor(group)
+}
+
+// Sync implements TopicSubscriptionUseCase.
+// Sync membandingkan langganan yang tersimpan dengan topic yang seharusnya diikuti setiap
+// perangkat user, lalu hanya mengirim subscribe/unsubscribe untuk selisihnya. Perangkat
+// yang sudah dihapus ikut di-unsubscribe. Kegagalan satu langganan tidak menghentikan
+// langganan lain; semua error dikembalikan bersama setelah seluruh selisih diproses.
+func (t *topicSubscriptionUseCase) Sync(ctx context.Context, userID uint) error {
+	user, err := t.userRepo.FindById(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	devices, err := t.deviceTokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	current, err := t.subscriptionRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	topics := desiredTopics(user)
+	desired := map[subscriptionKey]bool{}
+	for _, device := range devices {
+		for _, topic := range topics {
+			desired[subscriptionKey{token: device.Token, topic: topic}] = true
+		}
+	}
+
+	// topic lama blood_<type> tanpa rhesus cukup dibersihkan sekali per perangkat
+	for _, device := range devices {
+		if device.LegacyTopicsCleared {
+			continue
+		}
+
+		cleared := true
+		for _, topic := range bloodcompat.LegacyTopics() {
+			if err := t.fcmUseCase.UnsubscribeFromTopic(ctx, []string{device.Token}, topic); err != nil {
+				log.Printf("Failed to unsubscribe device %d from legacy topic %s: %v", device.ID, topic, err)
+				cleared = false
+			}
+		}
+
+		if !cleared {
+			continue
+		}
+
+		if err := t.deviceTokenRepo.MarkLegacyTopicsCleared(ctx, device.Token); err != nil {
+			log.Printf("Failed to mark legacy topics of device %d as cleared: %v", device.ID, err)
+		}
+	}
+
+	var errs []error
+
+	subscribed := map[subscriptionKey]bool{}
+	for _, sub := range current {
+		key := subscriptionKey{token: sub.FCMToken, topic: sub.Topic}
+		if desired[key] {
+			subscribed[key] = true
+			continue
+		}
+
+		// token yang sudah di-uninstall ditolak FCM, langganannya tetap dihapus dari database
+		if err := t.fcmUseCase.UnsubscribeFromTopic(ctx, []string{sub.FCMToken}, sub.Topic); err != nil {
+			log.Printf("Failed to unsubscribe subscription %d from topic %s: %v", sub.ID, sub.Topic, err)
+		}
+
+		if err := t.subscriptionRepo.Delete(ctx, sub.FCMToken, sub.Topic); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for key := range desired {
+		if subscribed[key] {
+			continue
+		}
+
+		if err := t.fcmUseCase.SubscribeToTopic(ctx, []string{key.token}, key.topic); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		subscription := &entity.TopicSubscription{
+			UserID:   userID,
+			FCMToken: key.token,
+			Topic:    key.topic,
+		}
+
+		if err := t.subscriptionRepo.Upsert(ctx, subscription); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Release implements TopicSubscriptionUseCase.
+// Release meng-unsubscribe perangkat dari semua topic pemilik lamanya dan menghapus langganannya,
+// dipakai saat token berpindah ke user lain agar perangkat tidak menerima broadcast milik user lama.
+func (t *topicSubscriptionUseCase) Release(ctx context.Context, token string) error {
+	subscriptions, err := t.subscriptionRepo.FindByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subscriptions {
+		if err := t.fcmUseCase.UnsubscribeFromTopic(ctx, []string{sub.FCMToken}, sub.Topic); err != nil {
+			log.Printf("Failed to unsubscribe subscription %d from topic %s: %v", sub.ID, sub.Topic, err)
+		}
+	}
+
+	return t.subscriptionRepo.DeleteByToken(ctx, token)
+}
@@ -0,0 +1,139 @@
+// Package bloodcompat memodelkan kecocokan golongan darah ABO dan Rhesus
+// antara pendonor dan penerima untuk transfusi sel darah merah.
+package bloodcompat
+
+const (
+	Positive = "positive"
+	Negative = "negative"
+)
+
+// BloodTypes dan RhesusTypes mengikuti enum blood_type dan rhesus di database
+var (
+	BloodTypes  = []string{"A", "B", "AB", "O"}
+	RhesusTypes = []string{Positive, Negative}
+)
+
+type Group struct {
+	BloodType string `json:"blood_type"`
+	Rhesus    string `json:"rhesus"`
+}
+
+// aboRecipients memetakan golongan ABO pendonor ke golongan ABO yang bisa menerima darahnya
+var aboRecipients = map[string][]string{
+	"O":  {"O", "A", "B", "AB"},
+	"A":  {"A", "AB"},
+	"B":  {"B", "AB"},
+	"AB": {"AB"},
+}
+
+func IsValidBloodType(bloodType string) bool {
+	_, ok := aboRecipients[bloodType]
+	return ok
+}
+
+func IsValidRhesus(rhesus string) bool {
+	return rhesus == Positive || rhesus == Negative
+}
+
+func IsValid(group Group) bool {
+	return IsValidBloodType(group.BloodType) && IsValidRhesus(group.Rhesus)
+}
+
+// CanDonateABO mengecek kecocokan ABO saja tanpa memperhitungkan rhesus
+func CanDonateABO(donorBloodType, recipientBloodType string) bool {
+	for _, recipient := range aboRecipients[donorBloodType] {
+		if recipient == recipientBloodType {
+			return true
+		}
+	}
+	return false
+}
+
+// CanDonate mengecek kecocokan ABO dan rhesus. Pendonor rhesus negatif bisa
+// memberi ke penerima positif maupun negatif, sebaliknya tidak.
+func CanDonate(donor, recipient Group) bool {
+	if !IsValid(donor) || !IsValid(recipient) {
+		return false
+	}
+
+	if !CanDonateABO(donor.BloodType, recipient.BloodType) {
+		return false
+	}
+
+	return donor.Rhesus == Negative || recipient.Rhesus == Positive
+}
+
+// DonorsFor mengembalikan semua golongan pendonor yang bisa memberi ke penerima
+func DonorsFor(recipient Group) []Group {
+	var groups []Group
+	for _, bloodType := range BloodTypes {
+		for _, rhesus := range RhesusTypes {
+			donor := Group{BloodType: bloodType, Rhesus: rhesus}
+			if CanDonate(donor, recipient) {
+				groups = append(groups, donor)
+			}
+		}
+	}
+	return groups
+}
+
+// RecipientsOf mengembalikan semua golongan penerima yang bisa dibantu pendonor
+func RecipientsOf(donor Group) []Group {
+	var groups []Group
+	for _, bloodType := range BloodTypes {
+		for _, rhesus := range RhesusTypes {
+			recipient := Group{BloodType: bloodType, Rhesus: rhesus}
+			if CanDonate(donor, recipient) {
+				groups = append(groups, recipient)
+			}
+		}
+	}
+	return groups
+}
+
+// DonorBloodTypesFor mengembalikan golongan ABO pendonor yang cocok untuk penerima,
+// dipakai ketika rhesus tidak diketahui
+func DonorBloodTypesFor(recipientBloodType string) []string {
+	var bloodTypes []string
+	for _, bloodType := range BloodTypes {
+		if CanDonateABO(bloodType, recipientBloodType) {
+			bloodTypes = append(bloodTypes, bloodType)
+		}
+	}
+	return bloodTypes
+}
+
+// Topic adalah nama topic FCM untuk penerima dengan golongan darah ini, misalnya blood_O_negative.
+// Pendonor berlangganan topic semua penerima yang bisa dibantunya, sehingga broadcast
+// untuk satu penerima cukup dikirim ke satu topic.
+func Topic(recipient Group) string {
+	return "blood_" + recipient.BloodType + "_" + recipient.Rhesus
+}
+
+// TopicsForDonor mengembalikan semua topic yang harus diikuti pendonor
+func TopicsForDonor(donor Group) []string {
+	var topics []string
+	for _, recipient := range RecipientsOf(donor) {
+		topics = append(topics, Topic(recipient))
+	}
+	return topics
+}
+
+// TopicsForBloodType mengembalikan topic penerima untuk kedua rhesus,
+// dipakai ketika rhesus penerima tidak diketahui
+func TopicsForBloodType(recipientBloodType string) []string {
+	var topics []string
+	for _, rhesus := range RhesusTypes {
+		topics = append(topics, Topic(Group{BloodType: recipientBloodType, Rhesus: rhesus}))
+	}
+	return topics
+}
+
+// LegacyTopics adalah topic lama blood_<type> tanpa rhesus yang sudah tidak dipakai broadcast
+func LegacyTopics() []string {
+	var topics []string
+	for _, bloodType := range BloodTypes {
+		topics = append(topics, "blood_"+bloodType)
+	}
+	return topics
+}
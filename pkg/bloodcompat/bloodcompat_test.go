@@ -0,0 +1,105 @@
+package bloodcompat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanDonate(t *testing.T) {
+	// baris: pendonor, kolom: penerima, urutan sesuai groups
+	groups := []Group{
+		{"O", Negative}, {"O", Positive},
+		{"A", Negative}, {"A", Positive},
+		{"B", Negative}, {"B", Positive},
+		{"AB", Negative}, {"AB", Positive},
+	}
+	matrix := [][]bool{
+		//           O-     O+     A-     A+     B-     B+     AB-    AB+
+		/* O-  */ {true, true, true, true, true, true, true, true},
+		/* O+  */ {false, true, false, true, false, true, false, true},
+		/* A-  */ {false, false, true, true, false, false, true, true},
+		/* A+  */ {false, false, false, true, false, false, false, true},
+		/* B-  */ {false, false, false, false, true, true, true, true},
+		/* B+  */ {false, false, false, false, false, true, false, true},
+		/* AB- */ {false, false, false, false, false, false, true, true},
+		/* AB+ */ {false, false, false, false, false, false, false, true},
+	}
+
+	for i, donor := range groups {
+		for j, recipient := range groups {
+			if got := CanDonate(donor, recipient); got != matrix[i][j] {
+				t.Errorf("CanDonate(%v, %v) = %v, want %v", donor, recipient, got, matrix[i][j])
+			}
+		}
+	}
+}
+
+func TestCanDonateInvalidGroup(t *testing.T) {
+	tests := []struct {
+		name             string
+		donor, recipient Group
+	}{
+		{"unknown blood type", Group{"C", Negative}, Group{"A", Positive}},
+		{"unknown rhesus", Group{"O", "unknown"}, Group{"A", Positive}},
+		{"empty recipient", Group{"O", Negative}, Group{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if CanDonate(tt.donor, tt.recipient) {
+				t.Errorf("CanDonate(%v, %v) = true, want false", tt.donor, tt.recipient)
+			}
+		})
+	}
+}
+
+func TestDonorsFor(t *testing.T) {
+	tests := []struct {
+		recipient Group
+		want      []Group
+	}{
+		{Group{"O", Negative}, []Group{{"O", Negative}}},
+		{Group{"A", Positive}, []Group{{"A", Positive}, {"A", Negative}, {"O", Positive}, {"O", Negative}}},
+		{Group{"AB", Negative}, []Group{{"A", Negative}, {"B", Negative}, {"AB", Negative}, {"O", Negative}}},
+	}
+
+	for _, tt := range tests {
+		if got := DonorsFor(tt.recipient); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DonorsFor(%v) = %v, want %v", tt.recipient, got, tt.want)
+		}
+	}
+}
+
+func TestRecipientsOf(t *testing.T) {
+	tests := []struct {
+		donor Group
+		want  []Group
+	}{
+		{Group{"AB", Positive}, []Group{{"AB", Positive}}},
+		{Group{"B", Negative}, []Group{{"B", Positive}, {"B", Negative}, {"AB", Positive}, {"AB", Negative}}},
+		{Group{"C", Negative}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := RecipientsOf(tt.donor); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("RecipientsOf(%v) = %v, want %v", tt.donor, got, tt.want)
+		}
+	}
+}
+
+func TestTopicsForDonor(t *testing.T) {
+	tests := []struct {
+		donor Group
+		want  []string
+	}{
+		{Group{"A", Positive}, []string{"blood_A_positive", "blood_AB_positive"}},
+		{Group{"AB", Negative}, []string{"blood_AB_positive", "blood_AB_negative"}},
+		{Group{"C", Negative}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := TopicsForDonor(tt.donor); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TopicsForDonor(%v) = %v, want %v", tt.donor, got, tt.want)
+		}
+	}
+}
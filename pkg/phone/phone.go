@@ -0,0 +1,52 @@
+// Package phone menormalkan nomor telepon ke format E.164 (+<kode negara><nomor>)
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// DefaultCountryCode dipakai untuk nomor lokal yang diawali 0
+const DefaultCountryCode = "62"
+
+var ErrInvalidNumber = errors.New("invalid phone number")
+
+// NormalizeE164 mengubah nomor seperti "0812-3456-7890", "62812..." atau "+62 812..."
+// menjadi "+6281234567890". Nomor lokal (diawali 0) dianggap memakai countryCode.
+func NormalizeE164(raw, countryCode string) (string, error) {
+	var digits strings.Builder
+	plus := false
+
+	for i, r := range strings.TrimSpace(raw) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			plus = true
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')':
+			// pemisah yang umum ditulis user
+		default:
+			return "", ErrInvalidNumber
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case plus:
+		// sudah memakai kode negara
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		number = countryCode + number[1:]
+	case !strings.HasPrefix(number, countryCode):
+		// nomor tanpa 0 di depan, mis. "81234567890"
+		number = countryCode + number
+	}
+
+	// E.164 maksimal 15 digit; nomor terpendek yang masuk akal sekitar 8 digit
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidNumber
+	}
+
+	return "+" + number, nil
+}
@@ -0,0 +1,60 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeE164(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"local with leading zero", "081234567890", "+6281234567890"},
+		{"local with separators", "0812-3456-7890", "+6281234567890"},
+		{"local with parentheses and dots", "(0812) 3456.7890", "+6281234567890"},
+		{"country code without plus", "6281234567890", "+6281234567890"},
+		{"plus with spaces", "+62 812 3456 7890", "+6281234567890"},
+		{"international prefix 00", "006581234567", "+6581234567"},
+		{"other country with plus", "+6581234567", "+6581234567"},
+		{"without leading zero", "81234567890", "+6281234567890"},
+		{"surrounding whitespace", "  081234567890 ", "+6281234567890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeE164(tt.raw, DefaultCountryCode)
+			if err != nil {
+				t.Fatalf("NormalizeE164(%q) returned error: %v", tt.raw, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeE164(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeE164Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"empty", ""},
+		{"letters", "0812abc7890"},
+		{"plus in the middle", "0812+34567890"},
+		{"too short", "0812"},
+		{"too long", "+6281234567890123"},
+		{"only plus", "+"},
+		{"plus with leading zero", "+0812345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeE164(tt.raw, DefaultCountryCode)
+			if !errors.Is(err, ErrInvalidNumber) {
+				t.Errorf("NormalizeE164(%q) = %q, %v, want ErrInvalidNumber", tt.raw, got, err)
+			}
+		})
+	}
+}